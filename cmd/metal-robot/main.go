@@ -12,10 +12,12 @@ import (
 
 	"github.com/metal-stack/metal-robot/pkg/clients"
 	"github.com/metal-stack/metal-robot/pkg/config"
+	"github.com/metal-stack/metal-robot/pkg/git"
 	"github.com/metal-stack/metal-robot/pkg/webhooks"
 	"github.com/metal-stack/v"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -156,7 +158,21 @@ func initLogging() {
 }
 
 func run(opts *Opts) error {
-	cs, err := clients.InitClients(logger, c.Clients)
+	var gitCache *git.Cache
+	if c.GitCache != nil {
+		var err error
+		gitCache, err = git.NewCache(c.GitCache.Path, c.GitCache.MaxSizeMB*1024*1024)
+		if err != nil {
+			return err
+		}
+		logger.Info("initialized git repository cache", "path", c.GitCache.Path, "max-size-mb", c.GitCache.MaxSizeMB)
+	}
+
+	if c.ExecPatch != nil {
+		logger.Info("enabled exec-patch modifier", "allowed-commands", c.ExecPatch.AllowedCommands)
+	}
+
+	cs, err := clients.InitClients(logger, c.Clients, gitCache)
 	if err != nil {
 		return err
 	}
@@ -166,6 +182,8 @@ func run(opts *Opts) error {
 		return err
 	}
 
+	http.Handle("/metrics", promhttp.Handler())
+
 	addr := fmt.Sprintf("%s:%d", opts.BindAddr, opts.Port)
 	logger.Info("starting metal-robot server", "version", v.V.String(), "address", addr)
 	server := http.Server{
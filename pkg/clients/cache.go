@@ -0,0 +1,145 @@
+package clients
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"sync"
+)
+
+const (
+	defaultCacheSize = 500
+
+	// responses larger than this are not cached in order to keep the memory footprint of the cache bounded
+	maxCacheEntrySize = 1 << 20
+)
+
+// cacheTransport caches responses of GET requests that carry an ETag and revalidates them with If-None-Match.
+// Github does not count 304 responses against the rate limit, so unchanged lists are almost free to fetch again.
+type cacheTransport struct {
+	base http.RoundTripper
+	size int
+
+	mtx     sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type cacheEntry struct {
+	key      string
+	etag     string
+	response []byte
+}
+
+func newCacheTransport(base http.RoundTripper, size int) *cacheTransport {
+	return &cacheTransport{
+		base:    base,
+		size:    size,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return t.base.RoundTrip(req)
+	}
+
+	key := cacheKey(req)
+
+	cached := t.get(key)
+	if cached != nil {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		cachedResp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(cached.response)), req)
+		if err != nil {
+			t.remove(key)
+			return resp, nil
+		}
+
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+
+		// the rate limit headers of the fresh response are more accurate than the cached ones
+		for k, v := range resp.Header {
+			cachedResp.Header[k] = v
+		}
+		cachedResp.Header.Set("X-From-Cache", "1")
+
+		return cachedResp, nil
+	}
+
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" || resp.ContentLength > maxCacheEntrySize {
+		return resp, nil
+	}
+
+	dump, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(dump) <= maxCacheEntrySize {
+		t.put(&cacheEntry{key: key, etag: etag, response: dump})
+	}
+
+	return resp, nil
+}
+
+func cacheKey(req *http.Request) string {
+	return req.Header.Get("Accept") + " " + req.URL.String()
+}
+
+func (t *cacheTransport) get(key string) *cacheEntry {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	e, ok := t.entries[key]
+	if !ok {
+		return nil
+	}
+
+	t.lru.MoveToFront(e)
+
+	return e.Value.(*cacheEntry)
+}
+
+func (t *cacheTransport) put(entry *cacheEntry) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if e, ok := t.entries[entry.key]; ok {
+		e.Value = entry
+		t.lru.MoveToFront(e)
+		return
+	}
+
+	t.entries[entry.key] = t.lru.PushFront(entry)
+
+	for t.lru.Len() > t.size {
+		oldest := t.lru.Back()
+		t.lru.Remove(oldest)
+		delete(t.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+func (t *cacheTransport) remove(key string) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if e, ok := t.entries[key]; ok {
+		t.lru.Remove(e)
+		delete(t.entries, key)
+	}
+}
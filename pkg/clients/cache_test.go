@@ -0,0 +1,80 @@
+package clients
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCacheTransport_RoundTrip(t *testing.T) {
+	var (
+		calls       int
+		notModified int
+		body        = `[{"id":1}]`
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+
+		if r.Header.Get("If-None-Match") == `"abc"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", `"abc"`)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	transport := newCacheTransport(http.DefaultTransport, 1)
+
+	get := func(path string) (string, *http.Response) {
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("RoundTrip() error = %v", err)
+		}
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return string(data), resp
+	}
+
+	got, resp := get("/a")
+	if got != body || resp.Header.Get("X-From-Cache") != "" {
+		t.Errorf("first response should come from server, got %q", got)
+	}
+
+	got, resp = get("/a")
+	if got != body || resp.StatusCode != http.StatusOK || resp.Header.Get("X-From-Cache") != "1" {
+		t.Errorf("second response should come from cache, got status %d and body %q", resp.StatusCode, got)
+	}
+	if notModified != 1 {
+		t.Errorf("expected one conditional request, got %d", notModified)
+	}
+
+	// evicts /a because the cache only holds a single entry
+	_, _ = get("/b")
+	_, resp = get("/a")
+	if resp.Header.Get("X-From-Cache") != "" {
+		t.Errorf("evicted response should not come from cache")
+	}
+
+	if calls != 4 {
+		t.Errorf("expected 4 calls to server, got %d", calls)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 
 	"github.com/metal-stack/metal-robot/pkg/config"
+	"github.com/metal-stack/metal-robot/pkg/git"
 )
 
 type ClientMap map[string]Client
@@ -14,7 +15,8 @@ type Client interface {
 	Organization() string
 }
 
-func InitClients(logger *slog.Logger, config []config.Client) (ClientMap, error) {
+// InitClients creates the configured clients. Github clients clone repositories through the given git cache, which may be nil.
+func InitClients(logger *slog.Logger, config []config.Client, gitCache *git.Cache) (ClientMap, error) {
 	cs := ClientMap{}
 	for _, clientConfig := range config {
 		ghConfig := clientConfig.GithubAuthConfig
@@ -30,6 +32,8 @@ func InitClients(logger *slog.Logger, config []config.Client) (ClientMap, error)
 				return nil, err
 			}
 
+			client.gitCache = gitCache
+
 			cs[clientConfig.Name] = client
 		}
 
@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"os"
+	"strings"
 
 	"github.com/bradleyfalzon/ghinstallation/v2"
 	"github.com/metal-stack/metal-robot/pkg/config"
+	"github.com/metal-stack/metal-robot/pkg/git"
 
 	"github.com/google/go-github/v79/github"
 
@@ -19,30 +22,83 @@ type Github struct {
 	keyPath        string
 	appID          int64
 	installationID int64
+	tokenPath      string
+	token          string
 	organizationID string
 	owner          string
 	atr            *ghinstallation.AppsTransport
-	itr            *ghinstallation.Transport
+	tr             http.RoundTripper
+	rateLimit      *rateLimitTransport
+	gitIdentity    git.Identity
+	gitCache       *git.Cache
 }
 
 func NewGithub(logger *slog.Logger, organizationID string, config *config.GithubClient) (*Github, error) {
+	return NewGithubWithTransport(logger, organizationID, config, http.DefaultTransport)
+}
+
+// NewGithubWithTransport returns a client that sends its requests through the given base transport, e.g. to route
+// them through a proxy.
+func NewGithubWithTransport(logger *slog.Logger, organizationID string, config *config.GithubClient, base http.RoundTripper) (*Github, error) {
+	var (
+		appAuth   = config.AppID != 0 || config.PrivateKeyCertPath != ""
+		tokenAuth = config.TokenPath != ""
+	)
+
+	if appAuth == tokenAuth {
+		return nil, fmt.Errorf("either github app auth (app-id and key-path) or token auth (token-path) must be configured for organization %q", organizationID)
+	}
+
+	gitIdentity, err := newGitIdentity(config)
+	if err != nil {
+		return nil, err
+	}
+
 	a := &Github{
 		logger:         logger,
 		keyPath:        config.PrivateKeyCertPath,
 		appID:          config.AppID,
+		tokenPath:      config.TokenPath,
 		organizationID: organizationID,
+		gitIdentity:    gitIdentity,
 	}
 
-	err := a.initClients()
+	if tokenAuth {
+		err = a.initTokenClients(base)
+	} else {
+		err = a.initClients(base)
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	var (
+		maxConcurrency = defaultMaxConcurrency
+		retries        = defaultRateLimitRetries
+		cacheSize      = defaultCacheSize
+	)
+	if config.MaxConcurrency != nil && *config.MaxConcurrency > 0 {
+		maxConcurrency = *config.MaxConcurrency
+	}
+	if config.RateLimitRetries != nil {
+		retries = *config.RateLimitRetries
+	}
+	if config.CacheSize != nil {
+		cacheSize = *config.CacheSize
+	}
+
+	a.rateLimit = newRateLimitTransport(logger, organizationID, a.tr, maxConcurrency, retries)
+	a.tr = a.rateLimit
+
+	if cacheSize > 0 {
+		a.tr = newCacheTransport(a.tr, cacheSize)
+	}
+
 	return a, nil
 }
 
-func (a *Github) initClients() error {
-	atr, err := ghinstallation.NewAppsTransportKeyFromFile(http.DefaultTransport, a.appID, a.keyPath)
+func (a *Github) initClients(base http.RoundTripper) error {
+	atr, err := ghinstallation.NewAppsTransportKeyFromFile(base, a.appID, a.keyPath)
 	if err != nil {
 		return fmt.Errorf("error creating github app client %w", err)
 	}
@@ -59,13 +115,39 @@ func (a *Github) initClients() error {
 	itr := ghinstallation.NewFromAppsTransport(atr, a.installationID)
 
 	a.atr = atr
-	a.itr = itr
+	a.tr = itr
 
 	a.logger.Info("successfully initialized github app client", "organization-id", a.organizationID, "installation-id", a.installationID, "expected-events", installation.Events)
 
 	return nil
 }
 
+func (a *Github) initTokenClients(base http.RoundTripper) error {
+	data, err := os.ReadFile(a.tokenPath)
+	if err != nil {
+		return fmt.Errorf("error reading github token %w", err)
+	}
+
+	a.token = strings.TrimSpace(string(data))
+	if a.token == "" {
+		return fmt.Errorf("github token in %q is empty", a.tokenPath)
+	}
+
+	a.tr = &tokenTransport{token: a.token, base: base}
+
+	// the users endpoint also resolves organizations, so this works for forks in personal accounts as well
+	account, _, err := a.GetV3Client().Users.Get(context.TODO(), a.organizationID)
+	if err != nil {
+		return fmt.Errorf("error finding organization %w", err)
+	}
+
+	a.owner = account.GetLogin()
+
+	a.logger.Info("successfully initialized github token client", "organization-id", a.organizationID)
+
+	return nil
+}
+
 func (a *Github) VCS() config.VCSType {
 	return config.Github
 }
@@ -75,18 +157,27 @@ func (a *Github) Organization() string {
 }
 
 func (a *Github) GetV3Client() *github.Client {
-	return github.NewClient(&http.Client{Transport: a.itr})
+	return github.NewClient(&http.Client{Transport: a.tr})
 }
 
+// GetV3AppClient returns a client authenticated as the github app itself. When using token auth,
+// there is no app and the returned client is authenticated with the token.
 func (a *Github) GetV3AppClient() *github.Client {
+	if a.atr == nil {
+		return a.GetV3Client()
+	}
 	return github.NewClient(&http.Client{Transport: a.atr})
 }
 
 func (a *Github) GetGraphQLClient() *githubv4.Client {
-	return githubv4.NewClient(&http.Client{Transport: a.itr})
+	return githubv4.NewClient(&http.Client{Transport: a.tr})
 }
 
 func (a *Github) GitToken(ctx context.Context) (string, error) {
+	if a.token != "" {
+		return a.token, nil
+	}
+
 	t, _, err := a.GetV3AppClient().Apps.CreateInstallationToken(ctx, a.installationID, &github.InstallationTokenOptions{})
 	if err != nil {
 		return "", fmt.Errorf("error creating installation token %w", err)
@@ -97,3 +188,77 @@ func (a *Github) GitToken(ctx context.Context) (string, error) {
 func (a *Github) Owner() string {
 	return a.owner
 }
+
+// RateLimit returns the rate limit budget reported by github with the last response of this client.
+func (a *Github) RateLimit() RateLimit {
+	return a.rateLimit.RateLimit()
+}
+
+// GitIdentity returns the identity used for authoring, committing and signing git objects created with this client.
+func (a *Github) GitIdentity() git.Identity {
+	return a.gitIdentity
+}
+
+// GitCache returns the on-disk cache for cloning repositories of this client, it is nil if the cache is not enabled.
+func (a *Github) GitCache() *git.Cache {
+	return a.gitCache
+}
+
+func newGitIdentity(c *config.GithubClient) (git.Identity, error) {
+	identity := git.DefaultIdentity()
+
+	if c.CommitAuthor != nil {
+		identity.Author.Name = c.CommitAuthor.Name
+		identity.Author.Email = c.CommitAuthor.Email
+		identity.Committer = identity.Author
+	}
+
+	if c.Committer != nil {
+		identity.Committer.Name = c.Committer.Name
+		identity.Committer.Email = c.Committer.Email
+	}
+
+	if c.Signing == nil {
+		return identity, nil
+	}
+
+	key, err := os.ReadFile(c.Signing.KeyPath)
+	if err != nil {
+		return identity, fmt.Errorf("error reading signing key %w", err)
+	}
+
+	var passphrase []byte
+	if c.Signing.PassphrasePath != "" {
+		passphrase, err = os.ReadFile(c.Signing.PassphrasePath)
+		if err != nil {
+			return identity, fmt.Errorf("error reading signing key passphrase %w", err)
+		}
+		passphrase = []byte(strings.TrimSpace(string(passphrase)))
+	}
+
+	switch f := c.Signing.Format; f {
+	case "ssh":
+		identity.Signer, err = git.NewSSHSigner(key, passphrase)
+	case "openpgp":
+		identity.Signer, err = git.NewOpenPGPSigner(key, passphrase)
+	default:
+		return identity, fmt.Errorf("unsupported signing format %q, must be one of ssh or openpgp", f)
+	}
+	if err != nil {
+		return identity, err
+	}
+
+	return identity, nil
+}
+
+// tokenTransport authenticates requests with a static personal access token or fine-grained token.
+type tokenTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "token "+t.token)
+	return t.base.RoundTrip(req)
+}
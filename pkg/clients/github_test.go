@@ -0,0 +1,137 @@
+package clients
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/metal-stack/metal-robot/pkg/config"
+)
+
+func TestNewGithub_TokenAuth(t *testing.T) {
+	var authorization []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = append(authorization, r.Header.Get("Authorization"))
+
+		if r.URL.Path != "/users/metal-stack" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"login":"metal-stack"}`))
+	}))
+	defer server.Close()
+
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	err = os.WriteFile(tokenPath, []byte("secret-token\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewGithubWithTransport(slog.New(slog.DiscardHandler), "metal-stack", &config.GithubClient{TokenPath: tokenPath}, &redirectTransport{target: target})
+	if err != nil {
+		t.Fatalf("NewGithubWithTransport() error = %v", err)
+	}
+
+	if client.Owner() != "metal-stack" {
+		t.Errorf("Owner() = %q, want %q", client.Owner(), "metal-stack")
+	}
+
+	token, err := client.GitToken(context.Background())
+	if err != nil {
+		t.Fatalf("GitToken() error = %v", err)
+	}
+	if token != "secret-token" {
+		t.Errorf("GitToken() = %q, want %q", token, "secret-token")
+	}
+
+	// without a github app, the app client falls back to the token client
+	_, _, err = client.GetV3AppClient().Users.Get(context.Background(), "metal-stack")
+	if err != nil {
+		t.Fatalf("GetV3AppClient() request error = %v", err)
+	}
+
+	if len(authorization) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(authorization))
+	}
+	for _, a := range authorization {
+		if a != "token secret-token" {
+			t.Errorf("Authorization header = %q, want %q", a, "token secret-token")
+		}
+	}
+}
+
+func TestNewGithub_Validation(t *testing.T) {
+	emptyToken := filepath.Join(t.TempDir(), "token")
+	err := os.WriteFile(emptyToken, []byte("\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		config *config.GithubClient
+	}{
+		{
+			name:   "neither app nor token auth",
+			config: &config.GithubClient{},
+		},
+		{
+			name:   "app and token auth",
+			config: &config.GithubClient{AppID: 1, PrivateKeyCertPath: "key.pem", TokenPath: "token"},
+		},
+		{
+			name:   "key path and token auth",
+			config: &config.GithubClient{PrivateKeyCertPath: "key.pem", TokenPath: "token"},
+		},
+		{
+			name:   "missing token file",
+			config: &config.GithubClient{TokenPath: filepath.Join(t.TempDir(), "missing")},
+		},
+		{
+			name:   "empty token file",
+			config: &config.GithubClient{TokenPath: emptyToken},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := http.RoundTripper(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				t.Errorf("unexpected request to %s", req.URL)
+				return nil, http.ErrNotSupported
+			}))
+
+			if _, err := NewGithubWithTransport(slog.New(slog.DiscardHandler), "metal-stack", tt.config, transport); err == nil {
+				t.Errorf("NewGithubWithTransport() expected an error")
+			}
+		})
+	}
+}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// redirectTransport sends all requests to the given target instead of the github api
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
@@ -0,0 +1,282 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	defaultMaxConcurrency   = 10
+	defaultRateLimitRetries = 3
+	maxRateLimitRetries     = 10
+
+	// github recommends to wait at least one minute when a secondary rate limit is hit without a retry-after header
+	defaultSecondaryRateLimitWait = time.Minute
+	// maxRateLimitWait is the longest time a request waits for a rate limit, requests that would need to wait longer
+	// fail with the rate limited response instead of blocking the caller, e.g. until a primary rate limit resets
+	maxRateLimitWait = 5 * time.Minute
+)
+
+var (
+	rateLimitRemaining = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "metal_robot",
+		Subsystem: "github",
+		Name:      "rate_limit_remaining",
+		Help:      "the number of requests remaining in the current github rate limit window",
+	}, []string{"organization", "resource"})
+	rateLimitLimit = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "metal_robot",
+		Subsystem: "github",
+		Name:      "rate_limit_limit",
+		Help:      "the maximum number of requests allowed in the current github rate limit window",
+	}, []string{"organization", "resource"})
+	rateLimitRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "metal_robot",
+		Subsystem: "github",
+		Name:      "rate_limit_retries_total",
+		Help:      "the number of requests that were retried because a github rate limit was hit",
+	}, []string{"organization"})
+)
+
+// RateLimit contains the rate limit information github returned with the last response.
+type RateLimit struct {
+	Resource  string
+	Limit     int
+	Remaining int
+	Used      int
+	Reset     time.Time
+}
+
+// rateLimitTransport bounds the number of concurrent requests against the github api and retries
+// requests that were rejected because of a primary or secondary rate limit.
+type rateLimitTransport struct {
+	logger       *slog.Logger
+	organization string
+	base         http.RoundTripper
+	sem          chan struct{}
+	retries      int
+
+	// wait is used for sleeping between retries, it can be replaced for testing purposes
+	wait func(ctx context.Context, d time.Duration) error
+
+	mtx         sync.RWMutex
+	last        RateLimit
+	pausedUntil time.Time
+}
+
+func newRateLimitTransport(logger *slog.Logger, organization string, base http.RoundTripper, maxConcurrency, retries int) *rateLimitTransport {
+	return &rateLimitTransport{
+		logger:       logger,
+		organization: organization,
+		base:         base,
+		sem:          make(chan struct{}, maxConcurrency),
+		retries:      min(retries, maxRateLimitRetries),
+		wait:         sleep,
+	}
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 0; ; attempt++ {
+		// waiting happens without holding a concurrency slot, such that requests of other callers can still fail fast
+		if d := time.Until(t.paused()); d > 0 {
+			if err := t.wait(ctx, d); err != nil {
+				return nil, err
+			}
+		}
+
+		r, err := rewindRequest(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := t.do(r)
+		if err != nil {
+			return nil, err
+		}
+
+		t.observe(resp.Header)
+
+		d, limited := retryAfter(resp, attempt)
+		if !limited {
+			return resp, nil
+		}
+
+		if attempt >= t.retries || (req.Body != nil && req.GetBody == nil) {
+			t.logger.Warn("github rate limit exceeded, giving up", "url", req.URL.String(), "status", resp.StatusCode, "attempts", attempt+1)
+			return resp, nil
+		}
+
+		if d > maxRateLimitWait {
+			t.logger.Warn("github rate limit exceeded, not retrying because the rate limit resets too late", "url", req.URL.String(), "status", resp.StatusCode, "wait", d.String())
+			return resp, nil
+		}
+
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(d).After(deadline) {
+			t.logger.Warn("github rate limit exceeded, not retrying because waiting exceeds request deadline", "url", req.URL.String(), "status", resp.StatusCode, "wait", d.String())
+			return resp, nil
+		}
+
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+
+		t.pause(d)
+
+		rateLimitRetries.WithLabelValues(t.organization).Inc()
+		t.logger.Warn("github rate limit exceeded, retrying request", "url", req.URL.String(), "status", resp.StatusCode, "wait", d.String(), "attempt", attempt+1)
+	}
+}
+
+// do sends the request while holding one of the concurrency slots of this transport.
+func (t *rateLimitTransport) do(req *http.Request) (*http.Response, error) {
+	select {
+	case t.sem <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	defer func() { <-t.sem }()
+
+	return t.base.RoundTrip(req)
+}
+
+// RateLimit returns the rate limit information of the last github response.
+func (t *rateLimitTransport) RateLimit() RateLimit {
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+	return t.last
+}
+
+func (t *rateLimitTransport) paused() time.Time {
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+	return t.pausedUntil
+}
+
+// pause makes all requests of this transport wait for the given duration, which is what github
+// expects when a secondary rate limit was hit.
+func (t *rateLimitTransport) pause(d time.Duration) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	until := time.Now().Add(d)
+	if until.After(t.pausedUntil) {
+		t.pausedUntil = until
+	}
+}
+
+func (t *rateLimitTransport) observe(h http.Header) {
+	limit, ok := parseRateLimit(h)
+	if !ok {
+		return
+	}
+
+	t.mtx.Lock()
+	t.last = limit
+	t.mtx.Unlock()
+
+	rateLimitRemaining.WithLabelValues(t.organization, limit.Resource).Set(float64(limit.Remaining))
+	rateLimitLimit.WithLabelValues(t.organization, limit.Resource).Set(float64(limit.Limit))
+
+	if limit.Limit > 0 && limit.Remaining < limit.Limit/10 {
+		t.logger.Warn("github rate limit almost exhausted", "resource", limit.Resource, "remaining", limit.Remaining, "limit", limit.Limit, "reset", limit.Reset)
+	} else {
+		t.logger.Debug("github rate limit", "resource", limit.Resource, "remaining", limit.Remaining, "limit", limit.Limit, "reset", limit.Reset)
+	}
+}
+
+func parseRateLimit(h http.Header) (RateLimit, bool) {
+	remaining, err := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return RateLimit{}, false
+	}
+
+	limit := RateLimit{
+		Resource:  h.Get("X-RateLimit-Resource"),
+		Remaining: remaining,
+	}
+
+	limit.Limit, _ = strconv.Atoi(h.Get("X-RateLimit-Limit"))
+	limit.Used, _ = strconv.Atoi(h.Get("X-RateLimit-Used"))
+
+	if reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		limit.Reset = time.Unix(reset, 0)
+	}
+
+	return limit, true
+}
+
+// retryAfter determines if the response was rejected because of a rate limit and how long to wait before retrying.
+func retryAfter(resp *http.Response, attempt int) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return max(time.Until(time.Unix(reset, 0)), time.Second), true
+		}
+	}
+
+	if resp.StatusCode == http.StatusForbidden && !isSecondaryRateLimit(resp) {
+		return 0, false
+	}
+
+	// exponential backoff as suggested by github when no further information is given
+	return min(defaultSecondaryRateLimitWait<<min(attempt, maxRateLimitRetries), maxRateLimitWait), true
+}
+
+func isSecondaryRateLimit(resp *http.Response) bool {
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+
+	return strings.Contains(strings.ToLower(string(body)), "secondary rate limit")
+}
+
+func rewindRequest(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 0 || req.Body == nil {
+		return req, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+
+	r := req.Clone(req.Context())
+	r.Body = body
+
+	return r, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,215 @@
+package clients
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRateLimitTransport_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		responses  []func(w http.ResponseWriter)
+		retries    int
+		wantStatus int
+		wantCalls  int32
+		wantWaits  []time.Duration
+	}{
+		{
+			name: "no rate limit",
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) {
+					w.Header().Set("X-RateLimit-Remaining", "4999")
+					w.WriteHeader(http.StatusOK)
+				},
+			},
+			retries:    3,
+			wantStatus: http.StatusOK,
+			wantCalls:  1,
+		},
+		{
+			name: "secondary rate limit with retry-after",
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) {
+					w.Header().Set("Retry-After", "30")
+					w.WriteHeader(http.StatusForbidden)
+				},
+				func(w http.ResponseWriter) {
+					w.WriteHeader(http.StatusOK)
+				},
+			},
+			retries:    3,
+			wantStatus: http.StatusOK,
+			wantCalls:  2,
+			wantWaits:  []time.Duration{30 * time.Second},
+		},
+		{
+			name: "secondary rate limit without headers backs off exponentially",
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) {
+					w.WriteHeader(http.StatusForbidden)
+					_, _ = w.Write([]byte(`{"message":"You have exceeded a secondary rate limit."}`))
+				},
+				func(w http.ResponseWriter) {
+					w.WriteHeader(http.StatusTooManyRequests)
+				},
+				func(w http.ResponseWriter) {
+					w.WriteHeader(http.StatusOK)
+				},
+			},
+			retries:    3,
+			wantStatus: http.StatusOK,
+			wantCalls:  3,
+			wantWaits:  []time.Duration{time.Minute, 2 * time.Minute},
+		},
+		{
+			name: "forbidden without rate limit is not retried",
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) {
+					w.WriteHeader(http.StatusForbidden)
+					_, _ = w.Write([]byte(`{"message":"Resource not accessible by integration"}`))
+				},
+			},
+			retries:    3,
+			wantStatus: http.StatusForbidden,
+			wantCalls:  1,
+		},
+		{
+			name: "exponential backoff is capped",
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) { w.WriteHeader(http.StatusTooManyRequests) },
+				func(w http.ResponseWriter) { w.WriteHeader(http.StatusTooManyRequests) },
+				func(w http.ResponseWriter) { w.WriteHeader(http.StatusTooManyRequests) },
+				func(w http.ResponseWriter) { w.WriteHeader(http.StatusTooManyRequests) },
+				func(w http.ResponseWriter) { w.WriteHeader(http.StatusTooManyRequests) },
+			},
+			retries:    4,
+			wantStatus: http.StatusTooManyRequests,
+			wantCalls:  5,
+			wantWaits:  []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute},
+		},
+		{
+			name: "primary rate limit resetting too late is not retried",
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) {
+					w.Header().Set("X-RateLimit-Remaining", "0")
+					w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+					w.WriteHeader(http.StatusForbidden)
+				},
+			},
+			retries:    3,
+			wantStatus: http.StatusForbidden,
+			wantCalls:  1,
+		},
+		{
+			name: "give up after retries",
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) {
+					w.Header().Set("Retry-After", "1")
+					w.WriteHeader(http.StatusTooManyRequests)
+				},
+				func(w http.ResponseWriter) {
+					w.Header().Set("Retry-After", "1")
+					w.WriteHeader(http.StatusTooManyRequests)
+				},
+			},
+			retries:    1,
+			wantStatus: http.StatusTooManyRequests,
+			wantCalls:  2,
+			wantWaits:  []time.Duration{time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if string(body) != "payload" {
+					t.Errorf("request body was not replayed, got %q", string(body))
+				}
+				n := calls.Add(1)
+				tt.responses[n-1](w)
+			}))
+			defer server.Close()
+
+			var waits []time.Duration
+
+			transport := newRateLimitTransport(slog.New(slog.DiscardHandler), "test", http.DefaultTransport, 1, tt.retries)
+			transport.wait = func(ctx context.Context, d time.Duration) error {
+				if len(transport.sem) != 0 {
+					t.Errorf("concurrency slot is held while waiting for the rate limit")
+				}
+				waits = append(waits, d.Round(time.Second))
+				transport.pausedUntil = time.Time{}
+				return nil
+			}
+
+			req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("payload"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("RoundTrip() error = %v", err)
+			}
+			_ = resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("RoundTrip() status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if calls.Load() != tt.wantCalls {
+				t.Errorf("RoundTrip() calls = %d, want %d", calls.Load(), tt.wantCalls)
+			}
+			if diff := cmp.Diff(tt.wantWaits, waits); diff != "" {
+				t.Errorf("RoundTrip() waits diff: %v", diff)
+			}
+		})
+	}
+}
+
+func Test_parseRateLimit(t *testing.T) {
+	h := http.Header{}
+	h.Set("X-RateLimit-Limit", "5000")
+	h.Set("X-RateLimit-Remaining", "4987")
+	h.Set("X-RateLimit-Used", "13")
+	h.Set("X-RateLimit-Reset", "1700000000")
+	h.Set("X-RateLimit-Resource", "core")
+
+	got, ok := parseRateLimit(h)
+	if !ok {
+		t.Fatal("parseRateLimit() expected rate limit to be parsed")
+	}
+
+	want := RateLimit{
+		Resource:  "core",
+		Limit:     5000,
+		Remaining: 4987,
+		Used:      13,
+		Reset:     time.Unix(1700000000, 0),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("parseRateLimit() diff: %v", diff)
+	}
+
+	if _, ok := parseRateLimit(http.Header{}); ok {
+		t.Errorf("parseRateLimit() expected no rate limit without headers")
+	}
+}
+
+func TestNewRateLimitTransport_CapsRetries(t *testing.T) {
+	transport := newRateLimitTransport(slog.New(slog.DiscardHandler), "test", http.DefaultTransport, 1, 1000)
+	if transport.retries != maxRateLimitRetries {
+		t.Errorf("retries = %d, want %d", transport.retries, maxRateLimitRetries)
+	}
+}
@@ -14,6 +14,13 @@ const (
 	ActionProjectV2ItemHandler        ActionName = "project-v2-item"
 )
 
+type CommitBackend string
+
+const (
+	CommitBackendGit CommitBackend = "git"
+	CommitBackendAPI CommitBackend = "api"
+)
+
 type WebhookActions []WebhookAction
 
 type WebhookAction struct {
@@ -34,12 +41,16 @@ type AggregateReleasesConfig struct {
 	TargetRepositoryURL  string                 `mapstructure:"repository-url" description:"the url of the target repo"`
 	Branch               *string                `mapstructure:"branch" description:"the branch to push in the target repo"`
 	BranchBase           *string                `mapstructure:"branch-base" description:"the base branch to raise the pull request against"`
-	CommitMsgTemplate    *string                `mapstructure:"commit-tpl" description:"template of the commit message"`
+	CommitMsgTemplate    *string                `mapstructure:"commit-tpl" description:"template of the commit message, either a format string with %s for the repository name and the tag or a go template"`
 	PullRequestTitle     *string                `mapstructure:"pull-request-title" description:"title of the pull request"`
+	CommitBackend        *string                `mapstructure:"commit-backend" description:"how changes are committed, either git (clone and push) or api (github api without a clone), defaults to git"`
 	SourceRepos          map[string]RepoActions `mapstructure:"repos" description:"the source repositories to trigger this action"`
+	DryRun               bool                   `mapstructure:"dry-run" description:"only logs the changes as diff instead of pushing them"`
 }
 
-type IssueCommentsHandlerConfig struct{}
+type IssueCommentsHandlerConfig struct {
+	SyncForkBuilds bool `mapstructure:"sync-fork-builds" description:"push new commits of approved fork pull requests to the fork build branch automatically"`
+}
 
 type LabelsOnCreation struct {
 	SourceRepos map[string]RepoActions `mapstructure:"repos" description:"the source repositories to trigger this action"`
@@ -58,10 +69,12 @@ type ProjectV2ItemHandlerConfig struct {
 type DistributeReleasesConfig struct {
 	SourceRepositoryName string       `mapstructure:"repository" description:"the name of the source repo"`
 	SourceRepositoryURL  string       `mapstructure:"repository-url" description:"the url of the source repo"`
-	BranchTemplate       *string      `mapstructure:"branch-template" description:"the branch to push in the target repos"`
-	CommitMsgTemplate    *string      `mapstructure:"commit-tpl" description:"template of the commit message in the target repos"`
+	BranchTemplate       *string      `mapstructure:"branch-template" description:"the branch to push in the target repos, either a format string with %s for the tag or a go template"`
+	CommitMsgTemplate    *string      `mapstructure:"commit-tpl" description:"template of the commit message in the target repos, either a format string with %s for the repository name and the tag or a go template"`
 	PullRequestTitle     *string      `mapstructure:"pull-request-title" description:"title of the pull request"`
+	CommitBackend        *string      `mapstructure:"commit-backend" description:"how changes are committed, either git (clone and push) or api (github api without a clone), defaults to git"`
 	TargetRepos          []TargetRepo `mapstructure:"repos" description:"the repositories that will be updated"`
+	DryRun               bool         `mapstructure:"dry-run" description:"only logs the changes as diff instead of pushing them"`
 }
 
 type YAMLTranslateReleasesConfig struct {
@@ -69,8 +82,9 @@ type YAMLTranslateReleasesConfig struct {
 	TargetRepositoryURL  string                       `mapstructure:"repository-url" description:"the url of the target repo"`
 	Branch               *string                      `mapstructure:"branch" description:"the branch to push in the target repo"`
 	BranchBase           *string                      `mapstructure:"branch-base" description:"the base branch to raise the pull request against"`
-	CommitMsgTemplate    *string                      `mapstructure:"commit-tpl" description:"template of the commit message"`
+	CommitMsgTemplate    *string                      `mapstructure:"commit-tpl" description:"template of the commit message, either a format string with %s for the repository name and the tag or a go template"`
 	PullRequestTitle     *string                      `mapstructure:"pull-request-title" description:"title of the pull request"`
+	CommitBackend        *string                      `mapstructure:"commit-backend" description:"how changes are committed, either git (clone and push) or api (github api without a clone), defaults to git"`
 	SourceRepos          map[string][]YAMLTranslation `mapstructure:"repos" description:"the source repositories to trigger this action"`
 }
 
@@ -85,10 +99,11 @@ type YAMLTranslationRead struct {
 }
 
 type TargetRepo struct {
-	RepositoryName string     `mapstructure:"repository" description:"the name of the target repo"`
-	RepositoryURL  string     `mapstructure:"repository-url" description:"the name of the target repo"`
-	Branch         string     `mapstructure:"branch" description:"the branch of the target repo to act on, defaults to master"`
-	Patches        []Modifier `mapstructure:"modifiers" description:"the name of the target repo"`
+	RepositoryName string               `mapstructure:"repository" description:"the name of the target repo"`
+	RepositoryURL  string               `mapstructure:"repository-url" description:"the name of the target repo"`
+	Branch         string               `mapstructure:"branch" description:"the branch of the target repo to act on, defaults to master"`
+	Patches        []Modifier           `mapstructure:"modifiers" description:"the name of the target repo"`
+	VersionPolicy  *VersionPolicyConfig `mapstructure:"version-policy" description:"restricts the releases that are distributed to the target repo, prereleases are skipped by default, increments are not supported"`
 }
 
 type ReleaseDraftConfig struct {
@@ -96,7 +111,7 @@ type ReleaseDraftConfig struct {
 	RepositoryName       string         `mapstructure:"repository" description:"the name of the release repo"`
 	Branch               *string        `mapstructure:"branch" description:"the branch considered for releases"`
 	BranchBase           *string        `mapstructure:"branch-base" description:"the base branch to raise the pull request against"`
-	ReleaseTitleTemplate *string        `mapstructure:"title-template" description:"custom template for the release title"`
+	ReleaseTitleTemplate *string        `mapstructure:"title-template" description:"custom template for the release title, either a format string with %s for the tag or a go template"`
 	DraftHeadline        *string        `mapstructure:"draft-headline" description:"custom headline for the release draft"`
 
 	MergedPRsHeadline    *string `mapstructure:"merged-prs-section-headline" description:"custom headline for the section of merged pull requests"`
@@ -15,9 +15,11 @@ const (
 )
 
 type Configuration struct {
-	Clients  []Client  `json:"clients" description:"client configurations"`
-	Webhooks []Webhook `json:"webhooks" description:"webhook configurations"`
-	Raw      []byte
+	Clients   []Client         `json:"clients" description:"client configurations"`
+	Webhooks  []Webhook        `json:"webhooks" description:"webhook configurations"`
+	GitCache  *GitCacheConfig  `json:"git-cache" description:"enables an on-disk cache for git repositories"`
+	ExecPatch *ExecPatchConfig `json:"exec-patch" description:"enables the exec-patch modifier for the allowed commands"`
+	Raw       []byte
 }
 
 type Client struct {
@@ -30,6 +32,34 @@ type Client struct {
 type GithubClient struct {
 	AppID              int64  `json:"app-id" description:"application id of github app"`
 	PrivateKeyCertPath string `json:"key-path" description:"private key pem path of github app"`
+	TokenPath          string `json:"token-path" description:"path to a file containing a personal access token or fine-grained token, alternative to github app authentication"`
+	MaxConcurrency     *int   `json:"max-concurrent-requests" description:"maximum number of concurrent requests against the github api for this client, defaults to 10"`
+	RateLimitRetries   *int   `json:"rate-limit-retries" description:"how often a request is retried when hitting a github rate limit, defaults to 3 and is capped at 10"`
+	CacheSize          *int   `json:"cache-size" description:"maximum number of github responses kept for conditional requests, defaults to 500, 0 disables the cache"`
+
+	CommitAuthor *GitIdentity `json:"commit-author" description:"author of the commits and tags created by this client"`
+	Committer    *GitIdentity `json:"committer" description:"committer of the commits created by this client, defaults to the commit author"`
+	Signing      *GitSigning  `json:"signing" description:"signs all commits and annotated tags created by this client"`
+}
+
+type GitIdentity struct {
+	Name  string `json:"name" description:"name of the git identity"`
+	Email string `json:"email" description:"email of the git identity"`
+}
+
+type GitSigning struct {
+	Format         string `json:"format" description:"format of the signing key, either ssh or openpgp"`
+	KeyPath        string `json:"key-path" description:"path to the private signing key"`
+	PassphrasePath string `json:"passphrase-path" description:"path to a file containing the passphrase of the signing key"`
+}
+
+type GitCacheConfig struct {
+	Path      string `json:"path" description:"directory in which the cached repositories are stored"`
+	MaxSizeMB int64  `json:"max-size-mb" description:"maximum size of the cache in megabytes, least recently used repositories are evicted when exceeded, zero means unlimited"`
+}
+
+type ExecPatchConfig struct {
+	AllowedCommands []string `json:"allowed-commands" description:"the commands that exec-patch modifiers are allowed to run"`
 }
 
 type GitlabClient struct {
@@ -5,15 +5,126 @@ type Modifier struct {
 	Args map[string]any `json:"args" description:"modifier configuration"`
 }
 
+// FileGlobConfig is decoded for all modifiers, such that the file of modifiers that patch files can be a glob pattern.
+type FileGlobConfig struct {
+	File       string `mapstructure:"file" description:"the name of the file to be patched, may be a glob pattern where ** matches any number of directories (e.g. deploy/*/values.yaml or docs/**/*.md), not supported by modifiers that operate on the whole repository"`
+	MinMatches *int   `mapstructure:"min-matches" description:"the minimum number of files that the glob pattern needs to match, defaults to 1"`
+}
+
 type LinePatchConfig struct {
-	File            string  `mapstructure:"file" description:"the name of the file to be patched"`
+	File            string  `mapstructure:"file" description:"the name of the file to be patched, may be a glob pattern"`
 	Line            int     `mapstructure:"line" description:"the line number in the file to be patched"`
-	ReplaceTemplate *string `mapstructure:"template" description:"a special template to be used for patching the line"`
+	ReplaceTemplate *string `mapstructure:"template" description:"a special template to be used for patching the line, either a format string with a single %s or a go template"`
 }
 
 type YAMLPathPatchConfig struct {
-	File           string  `mapstructure:"file" description:"the name of the file to be patched"`
-	YAMLPath       string  `mapstructure:"yaml-path" description:"the yaml path to the version"`
-	Template       *string `mapstructure:"template" description:"a special template to be used for patching the version"`
-	VersionCompare *bool   `mapstructure:"version-compare" description:"makes a version comparison before replacement and only replaces if version is greater than current"`
+	File           string               `mapstructure:"file" description:"the name of the file to be patched, may be a glob pattern"`
+	YAMLPath       string               `mapstructure:"yaml-path" description:"the yaml path to the version"`
+	Template       *string              `mapstructure:"template" description:"a special template to be used for patching the version, either a format string with a single %s or a go template"`
+	VersionCompare *bool                `mapstructure:"version-compare" description:"makes a version comparison before replacement and only replaces if version is greater than current"`
+	VersionPolicy  *VersionPolicyConfig `mapstructure:"version-policy" description:"restricts the versions that are updated to, applies with and without version comparison"`
+	Mode           *string              `mapstructure:"mode" description:"how the file is patched, either convert (converts the file to json and back, which reformats the file) or node (only replaces the targeted value and preserves comments and formatting), defaults to convert"`
+	Document       *int                 `mapstructure:"document" description:"the index of the document to patch in a multi-document yaml file, starting at 0"`
+	DocumentMatch  map[string]string    `mapstructure:"document-match" description:"only patches documents of a multi-document yaml file that contain the given values at the given yaml paths (e.g. kind: Deployment)"`
+}
+
+type RegexPatchConfig struct {
+	File          string  `mapstructure:"file" description:"the name of the file to be patched, may be a glob pattern"`
+	Regex         string  `mapstructure:"regex" description:"the regular expression to find the version in the file, may contain named capture groups"`
+	Group         *string `mapstructure:"group" description:"the named capture group of the regex that is replaced, if not given the full match is replaced"`
+	Template      *string `mapstructure:"template" description:"a special template to be used for patching the version, either a format string with a single %s or a go template"`
+	Occurrence    *string `mapstructure:"occurrence" description:"which matches to replace, either first or all, defaults to all"`
+	FailOnNoMatch *bool   `mapstructure:"fail-on-no-match" description:"return an error when the regex does not match anything in the file, defaults to true"`
+}
+
+type JSONPathPatchConfig struct {
+	File           string               `mapstructure:"file" description:"the name of the file to be patched, may be a glob pattern"`
+	JSONPath       string               `mapstructure:"json-path" description:"the json path to the version"`
+	Template       *string              `mapstructure:"template" description:"a special template to be used for patching the version, either a format string with a single %s or a go template"`
+	VersionCompare *bool                `mapstructure:"version-compare" description:"makes a version comparison before replacement and only replaces if version is greater than current"`
+	VersionPolicy  *VersionPolicyConfig `mapstructure:"version-policy" description:"restricts the versions that are updated to, applies with and without version comparison"`
+}
+
+type TOMLPathPatchConfig struct {
+	File           string               `mapstructure:"file" description:"the name of the file to be patched, may be a glob pattern"`
+	TOMLPath       string               `mapstructure:"toml-path" description:"the toml path to the version"`
+	Template       *string              `mapstructure:"template" description:"a special template to be used for patching the version, either a format string with a single %s or a go template"`
+	VersionCompare *bool                `mapstructure:"version-compare" description:"makes a version comparison before replacement and only replaces if version is greater than current"`
+	VersionPolicy  *VersionPolicyConfig `mapstructure:"version-policy" description:"restricts the versions that are updated to, applies with and without version comparison"`
+}
+
+type GoModPatchConfig struct {
+	File           *string              `mapstructure:"file" description:"the name of the go.mod file to be patched, defaults to go.mod"`
+	Module         string               `mapstructure:"module" description:"the module path of the released module, the major version suffix is derived from the released version"`
+	VersionCompare *bool                `mapstructure:"version-compare" description:"makes a version comparison before replacement and only replaces if version is greater than current"`
+	VersionPolicy  *VersionPolicyConfig `mapstructure:"version-policy" description:"restricts the versions that are updated to, applies with and without version comparison"`
+}
+
+type DockerfilePatchConfig struct {
+	File           *string              `mapstructure:"file" description:"the name of the dockerfile to be patched, defaults to Dockerfile"`
+	Image          *string              `mapstructure:"image" description:"the repository of the image in FROM instructions whose tag is patched (e.g. ghcr.io/metal-stack/builder), mutually exclusive with arg"`
+	Arg            *string              `mapstructure:"arg" description:"the name of the ARG instruction whose default value is patched, mutually exclusive with image"`
+	Template       *string              `mapstructure:"template" description:"a special template to be used for patching the version, either a format string with a single %s or a go template"`
+	VersionCompare *bool                `mapstructure:"version-compare" description:"makes a version comparison before replacement and only replaces if version is greater than current"`
+	VersionPolicy  *VersionPolicyConfig `mapstructure:"version-policy" description:"restricts the versions that are updated to, applies with and without version comparison"`
+}
+
+type HelmChartPatchConfig struct {
+	File             *string              `mapstructure:"file" description:"the path to the Chart.yaml to be patched, defaults to Chart.yaml"`
+	AppVersion       *bool                `mapstructure:"app-version" description:"patches the appVersion of the chart, charts without appVersion are left untouched, defaults to true"`
+	Dependency       *string              `mapstructure:"dependency" description:"the name of the chart dependency whose version is patched"`
+	VersionIncrement *string              `mapstructure:"version-increment" description:"increments the chart version when something was patched, one of major, minor or patch"`
+	Template         *string              `mapstructure:"template" description:"a special template to be used for patching the version, either a format string with a single %s or a go template"`
+	VersionCompare   *bool                `mapstructure:"version-compare" description:"makes a version comparison before replacement and only replaces if version is greater than current"`
+	VersionPolicy    *VersionPolicyConfig `mapstructure:"version-policy" description:"restricts the versions that are updated to, applies with and without version comparison"`
+}
+
+type KustomizeImagePatchConfig struct {
+	File           *string              `mapstructure:"file" description:"the path to the kustomization file to be patched, defaults to kustomization.yaml"`
+	Image          string               `mapstructure:"image" description:"the name of the image entry whose newTag is patched, the entry is added if it does not exist"`
+	NewName        *string              `mapstructure:"new-name" description:"the newName of the image entry in case the entry needs to be added"`
+	ClearDigest    *bool                `mapstructure:"clear-digest" description:"removes the digest of the image entry, which would otherwise take precedence over the new tag"`
+	Template       *string              `mapstructure:"template" description:"a special template to be used for patching the version, either a format string with a single %s or a go template"`
+	VersionCompare *bool                `mapstructure:"version-compare" description:"makes a version comparison before replacement and only replaces if version is greater than current"`
+	VersionPolicy  *VersionPolicyConfig `mapstructure:"version-policy" description:"restricts the versions that are updated to, applies with and without version comparison"`
+}
+
+type ActionsUsesPatchConfig struct {
+	Repository     string               `mapstructure:"repository" description:"the released repository in the form owner/repo whose uses references are updated in the workflows and composite actions"`
+	UpdatePinned   *bool                `mapstructure:"update-pinned" description:"updates references that are pinned to a commit sha to the commit of the new tag with a trailing version comment, defaults to false"`
+	VersionCompare *bool                `mapstructure:"version-compare" description:"makes a version comparison before replacement and only replaces if version is greater than current"`
+	VersionPolicy  *VersionPolicyConfig `mapstructure:"version-policy" description:"restricts the versions that are updated to, applies with and without version comparison, prereleases are skipped by default and never update floating refs like v1"`
+}
+
+type SubmodulePatchConfig struct {
+	Path   string  `mapstructure:"path" description:"the path of the submodule in the repository"`
+	URL    *string `mapstructure:"url" description:"the url of the submodule remote in which the release tag is resolved, defaults to the url from the .gitmodules file"`
+	Branch *string `mapstructure:"branch" description:"if set, the branch of the submodule is set to this value in the .gitmodules file"`
+}
+
+type VersionPolicyConfig struct {
+	Increments []string `mapstructure:"increments" description:"the allowed increments compared to the current version, any of major, minor and patch, defaults to all increments"`
+	Prerelease *string  `mapstructure:"prerelease" description:"how prereleases are handled, one of skip, allow or only, defaults to skip for target repos and the actions-uses-patch and allow for other modifiers"`
+	Constraint *string  `mapstructure:"constraint" description:"a semver constraint that the new version needs to satisfy (e.g. ~1.4 to only receive patch releases of the 1.4 line)"`
+	Pinned     bool     `mapstructure:"pinned" description:"the version is pinned and never updated"`
+}
+
+type ExecModifierConfig struct {
+	Command        string   `mapstructure:"command" description:"the command to run, which needs to be allowed in the exec-patch configuration"`
+	Args           []string `mapstructure:"args" description:"the arguments of the command, which are rendered as go templates with the release information"`
+	Files          []string `mapstructure:"files" description:"the files or glob patterns of files that are checked out for the command, defaults to all files of the repository"`
+	Timeout        *string  `mapstructure:"timeout" description:"the maximum duration of the command, defaults to 1m"`
+	ImportNewFiles *bool    `mapstructure:"import-new-files" description:"adds regular files that were created by the command to the repository, symlinks are never imported, defaults to false"`
+}
+
+type ChecksumPatchConfig struct {
+	File           string               `mapstructure:"file" description:"the name of the yaml file to be patched"`
+	YAMLPath       string               `mapstructure:"yaml-path" description:"the yaml path to the checksum, which needs to exist in the file"`
+	URL            string               `mapstructure:"url" description:"the url of the release asset, either a format string with a single %s for the tag or a go template"`
+	ChecksumsURL   *string              `mapstructure:"checksums-url" description:"the url of a checksums file in sha256sum format that contains the checksum of the asset, the asset is downloaded if not given"`
+	Template       *string              `mapstructure:"template" description:"a special template for the written checksum (e.g. sha256:%s), either a format string with a single %s or a go template"`
+	MaxSizeMB      *int64               `mapstructure:"max-size-mb" description:"the maximum size of the downloaded asset in megabytes, defaults to 256"`
+	VersionPath    *string              `mapstructure:"version-path" description:"the yaml path to the version that the checksum belongs to, which is used for the version comparison"`
+	VersionCompare *bool                `mapstructure:"version-compare" description:"only replaces the checksum if the released version is greater than or equal to the version at the version-path, such that the version can be patched by a preceding modifier"`
+	VersionPolicy  *VersionPolicyConfig `mapstructure:"version-policy" description:"restricts the versions that the checksum is updated for, applies with and without version comparison"`
 }
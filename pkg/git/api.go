@@ -0,0 +1,250 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v79/github"
+	"github.com/shurcooL/githubv4"
+)
+
+// APIBranch is a branch that is read through the contents api and committed through the graphql createCommitOnBranch mutation,
+// which does not require a clone and results in commits that are verified by github.
+// Commits are created with the head that was read as expected head, so concurrent modifications of the branch are detected.
+type APIBranch struct {
+	v3      *github.Client
+	v4      *githubv4.Client
+	owner   string
+	repo    string
+	branch  string
+	head    string
+	exists  bool
+	current map[string][]byte
+	changed map[string][]byte
+	files   []string
+}
+
+// NewAPIBranch opens the given branch of a repository. If the branch does not exist, it is created from the
+// default branch with the first commit.
+func NewAPIBranch(ctx context.Context, v3 *github.Client, v4 *githubv4.Client, owner, repo, branch string) (*APIBranch, error) {
+	b := &APIBranch{
+		v3:      v3,
+		v4:      v4,
+		owner:   owner,
+		repo:    repo,
+		branch:  branch,
+		exists:  true,
+		current: map[string][]byte{},
+		changed: map[string][]byte{},
+	}
+
+	ref, resp, err := v3.Git.GetRef(ctx, owner, repo, "refs/heads/"+branch)
+	if err != nil {
+		if resp == nil || resp.StatusCode != http.StatusNotFound {
+			return nil, fmt.Errorf("error finding branch %q: %w", branch, err)
+		}
+
+		repository, _, err := v3.Repositories.Get(ctx, owner, repo)
+		if err != nil {
+			return nil, fmt.Errorf("error finding repository: %w", err)
+		}
+
+		ref, _, err = v3.Git.GetRef(ctx, owner, repo, "refs/heads/"+repository.GetDefaultBranch())
+		if err != nil {
+			return nil, fmt.Errorf("error finding default branch %q: %w", repository.GetDefaultBranch(), err)
+		}
+
+		b.exists = false
+	}
+
+	b.head = ref.GetObject().GetSHA()
+
+	return b, nil
+}
+
+func (b *APIBranch) ReadFile(ctx context.Context, path string) ([]byte, error) {
+	if data, ok := b.changed[path]; ok {
+		return data, nil
+	}
+	if data, ok := b.current[path]; ok {
+		return data, nil
+	}
+
+	file, _, resp, err := b.v3.Repositories.GetContents(ctx, b.owner, b.repo, path, &github.RepositoryContentGetOptions{
+		Ref: b.head,
+	})
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("error reading repository file %s: %w", path, fs.ErrNotExist)
+		}
+		return nil, fmt.Errorf("error reading repository file: %w", err)
+	}
+	if file == nil {
+		return nil, fmt.Errorf("error reading repository file: %s is a directory", path)
+	}
+
+	var data []byte
+	if file.GetEncoding() == "none" {
+		// files larger than one megabyte are not returned by the contents api
+		data, _, err = b.v3.Git.GetBlobRaw(ctx, b.owner, b.repo, file.GetSHA())
+	} else {
+		var content string
+		content, err = file.GetContent()
+		data = []byte(content)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading repository file: %w", err)
+	}
+
+	b.current[path] = data
+
+	return data, nil
+}
+
+// WriteFile changes the content of a file, files that do not exist yet are added with the next commit.
+func (b *APIBranch) WriteFile(ctx context.Context, path string, data []byte) error {
+	if _, err := b.ReadFile(ctx, path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	b.changed[path] = data
+
+	return nil
+}
+
+func (b *APIBranch) ListFiles(ctx context.Context, dir string) ([]string, error) {
+	if b.files == nil {
+		tree, _, err := b.v3.Git.GetTree(ctx, b.owner, b.repo, b.head, true)
+		if err != nil {
+			return nil, fmt.Errorf("error listing repository files: %w", err)
+		}
+
+		if tree.GetTruncated() {
+			return nil, fmt.Errorf("error listing repository files: repository tree is too large")
+		}
+
+		b.files = []string{}
+		for _, entry := range tree.Entries {
+			if entry.GetType() == "blob" {
+				b.files = append(b.files, entry.GetPath())
+			}
+		}
+
+		sort.Strings(b.files)
+	}
+
+	all := slices.Clone(b.files)
+	for path := range b.changed {
+		if _, ok := b.current[path]; !ok {
+			all = append(all, path)
+		}
+	}
+
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+
+	var files []string
+	for _, f := range all {
+		if dir == "" || strings.HasPrefix(f, prefix) {
+			files = append(files, f)
+		}
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+}
+
+func (b *APIBranch) CommitAndPush(ctx context.Context, msg string) (string, error) {
+	var additions []githubv4.FileAddition
+	for path, data := range b.changed {
+		if current, ok := b.current[path]; ok && bytes.Equal(data, current) {
+			continue
+		}
+
+		additions = append(additions, githubv4.FileAddition{
+			Path:     githubv4.String(path),
+			Contents: githubv4.Base64String(base64.StdEncoding.EncodeToString(data)),
+		})
+	}
+
+	if len(additions) == 0 {
+		return "", ErrNoChanges
+	}
+
+	sort.Slice(additions, func(i, j int) bool {
+		return additions[i].Path < additions[j].Path
+	})
+
+	if !b.exists {
+		_, resp, err := b.v3.Git.CreateRef(ctx, b.owner, b.repo, github.CreateRef{
+			Ref: "refs/heads/" + b.branch,
+			SHA: b.head,
+		})
+		if err != nil {
+			// the branch was created concurrently, the commit needs to be based on its head instead
+			if resp != nil && resp.StatusCode == http.StatusUnprocessableEntity && strings.Contains(err.Error(), "Reference already exists") {
+				return "", fmt.Errorf("error creating branch %q: %w: %w", b.branch, ErrNonFastForward, err)
+			}
+			return "", fmt.Errorf("error creating branch %q: %w", b.branch, err)
+		}
+
+		b.exists = true
+	}
+
+	headline, body, _ := strings.Cut(msg, "\n")
+
+	input := githubv4.CreateCommitOnBranchInput{
+		Branch: githubv4.CommittableBranch{
+			RepositoryNameWithOwner: githubv4.NewString(githubv4.String(b.owner + "/" + b.repo)),
+			BranchName:              githubv4.NewString(githubv4.String(b.branch)),
+		},
+		Message: githubv4.CommitMessage{
+			Headline: githubv4.String(headline),
+		},
+		ExpectedHeadOid: githubv4.GitObjectID(b.head),
+		FileChanges: &githubv4.FileChanges{
+			Additions: &additions,
+		},
+	}
+
+	if body = strings.TrimSpace(body); body != "" {
+		input.Message.Body = githubv4.NewString(githubv4.String(body))
+	}
+
+	var m struct {
+		CreateCommitOnBranch struct {
+			Commit struct {
+				Oid githubv4.GitObjectID
+			}
+		} `graphql:"createCommitOnBranch(input: $input)"`
+	}
+
+	err := b.v4.Mutate(ctx, &m, input, nil)
+	if err != nil {
+		if strings.Contains(err.Error(), "Expected branch to point to") {
+			return "", fmt.Errorf("error creating commit on branch %q: %w: %w", b.branch, ErrNonFastForward, err)
+		}
+		return "", fmt.Errorf("error creating commit on branch %q: %w", b.branch, err)
+	}
+
+	b.head = string(m.CreateCommitOnBranch.Commit.Oid)
+	for path, data := range b.changed {
+		b.current[path] = data
+	}
+	b.changed = map[string][]byte{}
+	b.files = nil
+
+	if b.head == "" {
+		return "", errors.New("github did not return the created commit")
+	}
+
+	return b.head, nil
+}
@@ -0,0 +1,182 @@
+package git
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v79/github"
+	"github.com/shurcooL/githubv4"
+)
+
+func TestAPIBranch_CommitAndPush(t *testing.T) {
+	var (
+		createdRef string
+		mutation   map[string]any
+	)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/metal-stack/releases/git/ref/heads/auto-generate/v0.0.2", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+	})
+	mux.HandleFunc("GET /repos/metal-stack/releases", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"default_branch":"master"}`))
+	})
+	mux.HandleFunc("GET /repos/metal-stack/releases/git/ref/heads/master", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"ref":"refs/heads/master","object":{"sha":"1111111111111111111111111111111111111111"}}`))
+	})
+	mux.HandleFunc("GET /repos/metal-stack/releases/contents/release.yaml", func(w http.ResponseWriter, r *http.Request) {
+		if ref := r.URL.Query().Get("ref"); ref != "1111111111111111111111111111111111111111" {
+			t.Errorf("file read from unexpected ref %q", ref)
+		}
+		_, _ = w.Write([]byte(`{"type":"file","encoding":"base64","content":"` + base64.StdEncoding.EncodeToString([]byte("version: v0.0.1\n")) + `"}`))
+	})
+	mux.HandleFunc("POST /repos/metal-stack/releases/git/refs", func(w http.ResponseWriter, r *http.Request) {
+		var ref github.CreateRef
+		_ = json.NewDecoder(r.Body).Decode(&ref)
+		createdRef = ref.Ref + "@" + ref.SHA
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("POST /graphql", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Variables map[string]any `json:"variables"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mutation = body.Variables["input"].(map[string]any)
+		_, _ = w.Write([]byte(`{"data":{"createCommitOnBranch":{"commit":{"oid":"2222222222222222222222222222222222222222"}}}}`))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	baseURL, _ := url.Parse(server.URL + "/")
+	v3 := github.NewClient(nil)
+	v3.BaseURL = baseURL
+	v4 := githubv4.NewEnterpriseClient(server.URL+"/graphql", nil)
+
+	ctx := context.Background()
+
+	b, err := NewAPIBranch(ctx, v3, v4, "metal-stack", "releases", "auto-generate/v0.0.2")
+	if err != nil {
+		t.Fatalf("NewAPIBranch() error = %v", err)
+	}
+
+	content, err := b.ReadFile(ctx, "release.yaml")
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	err = b.WriteFile(ctx, "release.yaml", content)
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	_, err = b.CommitAndPush(ctx, "Bump nothing")
+	if !errors.Is(err, ErrNoChanges) {
+		t.Errorf("CommitAndPush() expected no changes, got %v", err)
+	}
+
+	err = b.WriteFile(ctx, "release.yaml", []byte("version: v0.0.2\n"))
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	// files that do not exist yet are added
+	err = b.WriteFile(ctx, "docs/release.md", []byte("# v0.0.2\n"))
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	hash, err := b.CommitAndPush(ctx, "Bump releases to version v0.0.2")
+	if err != nil {
+		t.Fatalf("CommitAndPush() error = %v", err)
+	}
+
+	if hash != "2222222222222222222222222222222222222222" {
+		t.Errorf("CommitAndPush() returned unexpected hash %q", hash)
+	}
+
+	if createdRef != "refs/heads/auto-generate/v0.0.2@1111111111111111111111111111111111111111" {
+		t.Errorf("unexpected branch creation %q", createdRef)
+	}
+
+	want := map[string]any{
+		"branch": map[string]any{
+			"repositoryNameWithOwner": "metal-stack/releases",
+			"branchName":              "auto-generate/v0.0.2",
+		},
+		"message": map[string]any{
+			"headline": "Bump releases to version v0.0.2",
+		},
+		"expectedHeadOid": "1111111111111111111111111111111111111111",
+		"fileChanges": map[string]any{
+			"additions": []any{
+				map[string]any{
+					"path":     "docs/release.md",
+					"contents": base64.StdEncoding.EncodeToString([]byte("# v0.0.2\n")),
+				},
+				map[string]any{
+					"path":     "release.yaml",
+					"contents": base64.StdEncoding.EncodeToString([]byte("version: v0.0.2\n")),
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, mutation); diff != "" {
+		t.Errorf("CommitAndPush() mutation diff: %v", diff)
+	}
+}
+
+func TestAPIBranch_CommitAndPush_BranchCreatedConcurrently(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/metal-stack/releases/git/ref/heads/auto-generate/v0.0.2", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+	})
+	mux.HandleFunc("GET /repos/metal-stack/releases", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"default_branch":"master"}`))
+	})
+	mux.HandleFunc("GET /repos/metal-stack/releases/git/ref/heads/master", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"ref":"refs/heads/master","object":{"sha":"1111111111111111111111111111111111111111"}}`))
+	})
+	mux.HandleFunc("POST /repos/metal-stack/releases/git/refs", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"message":"Reference already exists"}`))
+	})
+	mux.HandleFunc("POST /graphql", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("no commit must be created when the branch creation failed")
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	baseURL, _ := url.Parse(server.URL + "/")
+	v3 := github.NewClient(nil)
+	v3.BaseURL = baseURL
+	v4 := githubv4.NewEnterpriseClient(server.URL+"/graphql", nil)
+
+	ctx := context.Background()
+
+	b, err := NewAPIBranch(ctx, v3, v4, "metal-stack", "releases", "auto-generate/v0.0.2")
+	if err != nil {
+		t.Fatalf("NewAPIBranch() error = %v", err)
+	}
+
+	err = b.WriteFile(ctx, "release.yaml", []byte("version: v0.0.2\n"))
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	_, err = b.CommitAndPush(ctx, "Bump releases to version v0.0.2")
+	if !errors.Is(err, ErrNonFastForward) {
+		t.Errorf("CommitAndPush() expected non-fast-forward error, got %v", err)
+	}
+}
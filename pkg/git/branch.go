@@ -0,0 +1,55 @@
+package git
+
+import (
+	"context"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// Branch gives access to the files of a repository branch and commits changes back to it.
+// Its methods match the file patcher content reader and writer, such that patches can be applied directly.
+type Branch interface {
+	ReadFile(ctx context.Context, path string) ([]byte, error)
+	WriteFile(ctx context.Context, path string, data []byte) error
+	// ListFiles returns the paths of all files below the given directory, an empty directory lists the entire repository
+	ListFiles(ctx context.Context, dir string) ([]string, error)
+	// CommitAndPush commits all changes and returns the hash of the new commit. It returns ErrNoChanges in case nothing was changed.
+	CommitAndPush(ctx context.Context, msg string) (string, error)
+}
+
+// RepositoryBranch is a branch of a cloned repository, changes are pushed with git.
+type RepositoryBranch struct {
+	r        *git.Repository
+	identity Identity
+}
+
+func NewRepositoryBranch(r *git.Repository, identity Identity) *RepositoryBranch {
+	return &RepositoryBranch{
+		r:        r,
+		identity: identity,
+	}
+}
+
+func (b *RepositoryBranch) ReadFile(_ context.Context, path string) ([]byte, error) {
+	return ReadRepoFile(b.r, path)
+}
+
+func (b *RepositoryBranch) WriteFile(_ context.Context, path string, data []byte) error {
+	return WriteRepoFile(b.r, path, data)
+}
+
+func (b *RepositoryBranch) ListFiles(_ context.Context, dir string) ([]string, error) {
+	return ListRepoFiles(b.r, dir)
+}
+
+func (b *RepositoryBranch) ReadSubmodule(path string) (string, error) {
+	return ReadSubmoduleCommit(b.r, path)
+}
+
+func (b *RepositoryBranch) WriteSubmodule(path, commit string) error {
+	return WriteSubmoduleCommit(b.r, path, commit)
+}
+
+func (b *RepositoryBranch) CommitAndPush(_ context.Context, msg string) (string, error) {
+	return CommitAndPush(b.r, msg, b.identity)
+}
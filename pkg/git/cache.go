@@ -0,0 +1,425 @@
+package git
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+const (
+	// cached repositories that were used recently are never evicted because operations may still read from them
+	cacheEvictionGracePeriod = 10 * time.Minute
+	// reads refresh the modification time of a cached repository at most once within this interval
+	cacheTouchInterval = time.Minute
+)
+
+// Cache keeps bare repositories on disk, such that consecutive clones of the same repository
+// only need to fetch the changes of a single branch instead of the entire repository.
+type Cache struct {
+	path    string
+	maxSize int64
+
+	mtx sync.Mutex
+	// locks are held exclusively while a cached repository is updated or evicted and shared while objects are read from it
+	locks map[string]*sync.RWMutex
+}
+
+// NewCache returns an on-disk repository cache in the given directory. A maxSize of zero means that the cache size is unlimited.
+func NewCache(path string, maxSize int64) (*Cache, error) {
+	if path == "" {
+		return nil, fmt.Errorf("git cache path must be specified")
+	}
+
+	err := os.MkdirAll(path, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("error creating git cache directory: %w", err)
+	}
+
+	return &Cache{
+		path:    path,
+		maxSize: maxSize,
+		locks:   map[string]*sync.RWMutex{},
+	}, nil
+}
+
+// Clone returns a repository with an in-memory worktree for the given branch like ShallowClone, but the branch is fetched
+// incrementally into the cache. Objects are read from the cached bare repository, new objects are only kept in memory,
+// so every operation gets its own isolated worktree.
+func (c *Cache) Clone(repoURL string, branch string) (*git.Repository, error) {
+	remoteURL, auth, err := splitCredentials(repoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	key := cacheKey(remoteURL)
+	dir := filepath.Join(c.path, key)
+
+	hash, err := c.update(key, dir, remoteURL, auth, branch)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.evict(key)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &layeredStorage{
+		Storage:  memory.NewStorage(),
+		fallback: filesystem.NewStorage(osfs.New(dir), cache.NewObjectLRUDefault()),
+		lock:     c.lock(key),
+		dir:      dir,
+	}
+
+	r, err := git.Init(s, memfs.New())
+	if err != nil {
+		return nil, fmt.Errorf("error initializing git repo: %w", err)
+	}
+
+	_, err = r.CreateRemote(&config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{repoURL},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating remote: %w", err)
+	}
+
+	ref := plumbing.NewHashReference(plumbing.NewBranchReferenceName(branch), hash)
+	err = s.SetReference(ref)
+	if err != nil {
+		return nil, fmt.Errorf("error creating branch: %w", err)
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving git worktree: %w", err)
+	}
+
+	err = w.Checkout(&git.CheckoutOptions{
+		Branch: ref.Name(),
+		Force:  true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error during git checkout: %w", err)
+	}
+
+	return r, nil
+}
+
+// update fetches the given branch into the cached bare repository and returns its head. If the branch does not
+// exist in the remote repository, the head of the default branch is returned.
+func (c *Cache) update(key, dir, remoteURL string, auth transport.AuthMethod, branch string) (plumbing.Hash, error) {
+	lock := c.lock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	r, err := git.PlainOpen(dir)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		r, err = git.PlainInit(dir, true)
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("error initializing cached git repo: %w", err)
+		}
+
+		_, err = r.CreateRemote(&config.RemoteConfig{
+			Name: "origin",
+			URLs: []string{remoteURL},
+		})
+	}
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("error opening cached git repo: %w", err)
+	}
+
+	remote, err := r.Remote("origin")
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("error finding remote of cached git repo: %w", err)
+	}
+
+	refs, err := remote.List(&git.ListOptions{Auth: auth})
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("error listing repository refs: %w", err)
+	}
+
+	fetchBranch := plumbing.NewBranchReferenceName(branch)
+	if !containsReference(refs, fetchBranch) {
+		head := findReference(refs, plumbing.HEAD)
+		if head == nil || head.Type() != plumbing.SymbolicReference {
+			return plumbing.ZeroHash, fmt.Errorf("unable to determine default branch of repository")
+		}
+		fetchBranch = head.Target()
+	}
+
+	err = remote.Fetch(&git.FetchOptions{
+		RefSpecs: []config.RefSpec{config.RefSpec("+" + fetchBranch + ":" + fetchBranch)},
+		Auth:     auth,
+		Tags:     git.NoTags,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return plumbing.ZeroHash, fmt.Errorf("error fetching repository refs: %w", err)
+	}
+
+	ref, err := r.Reference(fetchBranch, true)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("error finding fetched branch: %w", err)
+	}
+
+	now := time.Now()
+	_ = os.Chtimes(dir, now, now)
+
+	return ref.Hash(), nil
+}
+
+func (c *Cache) lock(key string) *sync.RWMutex {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.lockLocked(key)
+}
+
+func (c *Cache) lockLocked(key string) *sync.RWMutex {
+	l, ok := c.locks[key]
+	if !ok {
+		l = &sync.RWMutex{}
+		c.locks[key] = l
+	}
+
+	return l
+}
+
+// evict removes the least recently used repositories until the cache fits into its maximum size.
+func (c *Cache) evict(current string) error {
+	if c.maxSize <= 0 {
+		return nil
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	entries, err := os.ReadDir(c.path)
+	if err != nil {
+		return fmt.Errorf("error reading git cache directory: %w", err)
+	}
+
+	type cached struct {
+		key     string
+		size    int64
+		modTime time.Time
+	}
+
+	var (
+		repos []cached
+		total int64
+	)
+
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			return err
+		}
+
+		size, err := dirSize(filepath.Join(c.path, e.Name()))
+		if err != nil {
+			return err
+		}
+
+		total += size
+		repos = append(repos, cached{key: e.Name(), size: size, modTime: info.ModTime()})
+	}
+
+	sort.Slice(repos, func(i, j int) bool {
+		return repos[i].modTime.Before(repos[j].modTime)
+	})
+
+	for _, repo := range repos {
+		if total <= c.maxSize {
+			break
+		}
+
+		if repo.key == current || time.Since(repo.modTime) < cacheEvictionGracePeriod {
+			continue
+		}
+
+		evicted, err := c.remove(repo.key)
+		if err != nil {
+			return fmt.Errorf("error evicting cached git repo: %w", err)
+		}
+
+		if evicted {
+			total -= repo.size
+		}
+	}
+
+	return nil
+}
+
+// remove deletes the cached repository unless it is currently updated or read from or was used within the grace period.
+// The cache mutex must be held by the caller.
+func (c *Cache) remove(key string) (bool, error) {
+	l := c.lockLocked(key)
+	if !l.TryLock() {
+		return false, nil
+	}
+	defer l.Unlock()
+
+	dir := filepath.Join(c.path, key)
+
+	// the repository might have been used after the cache directory was read
+	info, err := os.Stat(dir)
+	if err != nil {
+		return false, err
+	}
+	if time.Since(info.ModTime()) < cacheEvictionGracePeriod {
+		return false, nil
+	}
+
+	err = os.RemoveAll(dir)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func dirSize(path string) (int64, error) {
+	var size int64
+
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		size += info.Size()
+
+		return nil
+	})
+
+	return size, err
+}
+
+// splitCredentials removes the credentials from the url, such that they are not persisted in the cache
+func splitCredentials(repoURL string) (string, transport.AuthMethod, error) {
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to parse repository url: %w", err)
+	}
+
+	if u.User == nil {
+		return u.String(), nil, nil
+	}
+
+	password, _ := u.User.Password()
+	auth := &githttp.BasicAuth{
+		Username: u.User.Username(),
+		Password: password,
+	}
+
+	u.User = nil
+
+	return u.String(), auth, nil
+}
+
+func cacheKey(remoteURL string) string {
+	sum := sha256.Sum256([]byte(remoteURL))
+	return hex.EncodeToString(sum[:])
+}
+
+func containsReference(refs []*plumbing.Reference, name plumbing.ReferenceName) bool {
+	return findReference(refs, name) != nil
+}
+
+func findReference(refs []*plumbing.Reference, name plumbing.ReferenceName) *plumbing.Reference {
+	for _, ref := range refs {
+		if ref.Name() == name {
+			return ref
+		}
+	}
+	return nil
+}
+
+// layeredStorage keeps all writes in memory and falls back to the cached repository for reading objects.
+// Reads from the cached repository hold its lock, such that it is not evicted while being read, and refresh
+// its modification time, such that it is not evicted between the reads of a long running operation.
+type layeredStorage struct {
+	*memory.Storage
+	fallback *filesystem.Storage
+	lock     *sync.RWMutex
+	dir      string
+	// touched is the unix time of the last modification time refresh
+	touched atomic.Int64
+}
+
+// rlock acquires the read lock of the cached repository and refreshes its modification time.
+// The repository cannot be evicted while the read lock is held, so it still exists when it is touched.
+func (s *layeredStorage) rlock() {
+	s.lock.RLock()
+
+	now := time.Now()
+	last := s.touched.Load()
+	if now.Sub(time.Unix(last, 0)) < cacheTouchInterval || !s.touched.CompareAndSwap(last, now.Unix()) {
+		return
+	}
+
+	_ = os.Chtimes(s.dir, now, now)
+}
+
+func (s *layeredStorage) EncodedObject(t plumbing.ObjectType, h plumbing.Hash) (plumbing.EncodedObject, error) {
+	obj, err := s.Storage.EncodedObject(t, h)
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		s.rlock()
+		defer s.lock.RUnlock()
+		return s.fallback.EncodedObject(t, h)
+	}
+	return obj, err
+}
+
+func (s *layeredStorage) HasEncodedObject(h plumbing.Hash) error {
+	err := s.Storage.HasEncodedObject(h)
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		s.rlock()
+		defer s.lock.RUnlock()
+		return s.fallback.HasEncodedObject(h)
+	}
+	return err
+}
+
+func (s *layeredStorage) EncodedObjectSize(h plumbing.Hash) (int64, error) {
+	size, err := s.Storage.EncodedObjectSize(h)
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		s.rlock()
+		defer s.lock.RUnlock()
+		return s.fallback.EncodedObjectSize(h)
+	}
+	return size, err
+}
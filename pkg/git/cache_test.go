@@ -0,0 +1,199 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+func TestCache_Clone(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("local git transport requires git binary")
+	}
+
+	upstream := t.TempDir()
+	upstreamRepo, err := git.PlainInit(upstream, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commitFile(t, upstreamRepo, upstream, "release.yaml", "version: v0.0.1\n")
+
+	c, err := NewCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// branch does not exist in upstream, so it is created from the default branch
+	r, err := c.Clone(upstream, "auto-generate/v0.0.2")
+	if err != nil {
+		t.Fatalf("Cache.Clone() error = %v", err)
+	}
+
+	content, err := ReadRepoFile(r, "release.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "version: v0.0.1\n" {
+		t.Errorf("unexpected file content %q", string(content))
+	}
+
+	err = WriteRepoFile(r, "release.yaml", []byte("version: v0.0.2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = CommitAndPush(r, "Bump version", DefaultIdentity())
+	if err != nil {
+		t.Fatalf("CommitAndPush() error = %v", err)
+	}
+
+	// the default branch moves on upstream and is fetched incrementally into the cache
+	commitFile(t, upstreamRepo, upstream, "release.yaml", "version: v0.0.3\n")
+
+	for branch, want := range map[string]string{
+		"auto-generate/v0.0.2": "version: v0.0.2\n",
+		"master":               "version: v0.0.3\n",
+	} {
+		r, err := c.Clone(upstream, branch)
+		if err != nil {
+			t.Fatalf("Cache.Clone() error = %v", err)
+		}
+
+		content, err := ReadRepoFile(r, "release.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != want {
+			t.Errorf("unexpected file content on branch %s: %q", branch, string(content))
+		}
+	}
+}
+
+func TestCache_Evict(t *testing.T) {
+	tests := []struct {
+		name string
+		busy []string
+		want map[string]bool
+	}{
+		{
+			name: "evict least recently used repositories",
+			want: map[string]bool{"a": true, "b": false, "c": false},
+		},
+		{
+			name: "skip repositories that are read from",
+			busy: []string{"b"},
+			want: map[string]bool{"a": true, "b": true, "c": false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			c := &Cache{path: dir, maxSize: 10, locks: map[string]*sync.RWMutex{}}
+
+			old := time.Now().Add(-time.Hour)
+			for _, key := range []string{"a", "b", "c"} {
+				err := os.MkdirAll(filepath.Join(dir, key), 0755)
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = os.WriteFile(filepath.Join(dir, key, "pack"), []byte("12345678"), 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = os.Chtimes(filepath.Join(dir, key), old, old)
+				if err != nil {
+					t.Fatal(err)
+				}
+				old = old.Add(time.Minute)
+			}
+
+			for _, key := range tt.busy {
+				l := c.lock(key)
+				l.RLock()
+				defer l.RUnlock()
+			}
+
+			err := c.evict("a")
+			if err != nil {
+				t.Fatalf("evict() error = %v", err)
+			}
+
+			for key, exists := range tt.want {
+				_, err := os.Stat(filepath.Join(dir, key))
+				if exists != (err == nil) {
+					t.Errorf("expected existence of %s to be %t", key, exists)
+				}
+			}
+		})
+	}
+}
+
+func TestLayeredStorage_RefreshesModTime(t *testing.T) {
+	dir := t.TempDir()
+
+	old := time.Now().Add(-time.Hour)
+	err := os.Chtimes(dir, old, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &layeredStorage{
+		Storage:  memory.NewStorage(),
+		fallback: filesystem.NewStorage(osfs.New(dir), cache.NewObjectLRUDefault()),
+		lock:     &sync.RWMutex{},
+		dir:      dir,
+	}
+
+	// the object does not exist, but the lookup falls back to the cached repository
+	_ = s.HasEncodedObject(plumbing.ZeroHash)
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(info.ModTime()) >= cacheEvictionGracePeriod {
+		t.Errorf("expected modification time of cached repository to be refreshed, got %s", info.ModTime())
+	}
+}
+
+func commitFile(t *testing.T, r *git.Repository, path, file, content string) {
+	err := os.WriteFile(filepath.Join(path, file), []byte(content), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = w.Add(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = w.Commit("update "+file, &git.CommitOptions{
+		Author: &object.Signature{Name: defaultAuthor, Email: defaultAuthorMail, When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = r.Reference(plumbing.Master, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -3,7 +3,11 @@ package git
 import (
 	"fmt"
 	"io"
-	"time"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"errors"
 
@@ -12,11 +16,17 @@ import (
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
-	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
 	"github.com/go-git/go-git/v5/storage/memory"
 )
 
-var ErrNoChanges = fmt.Errorf("no changes")
+var (
+	ErrNoChanges = fmt.Errorf("no changes")
+	// ErrNonFastForward indicates that the branch was modified concurrently and the push was rejected
+	ErrNonFastForward = fmt.Errorf("non-fast-forward update")
+)
 
 const (
 	defaultLocalRef   = "refs/heads"
@@ -24,6 +34,7 @@ const (
 	defaultAuthorMail = "info@metal-stack.io"
 )
 
+// ShallowClone clones the given branch of a repository into memory. If the branch does not exist, it is created from the default branch.
 func ShallowClone(url string, branch string, depth int) (*git.Repository, error) {
 	r, err := git.Clone(memory.NewStorage(), memfs.New(), &git.CloneOptions{
 		URL:   url,
@@ -99,24 +110,86 @@ func PushToRemote(remoteURL, remoteBranch, targetURL, targetBranch, msg string)
 	return nil
 }
 
-func DeleteBranch(repoURL, branch string) error {
-	r, err := git.Clone(memory.NewStorage(), memfs.New(), &git.CloneOptions{
-		URL:   repoURL,
-		Depth: 1,
+// PushCommitToRemote pushes the given commit of a remote branch to a branch of the target repository. In contrast to
+// PushToRemote, commits that were pushed to the remote branch in the meantime are not pushed. The commit must be
+// contained in the history of the remote branch.
+func PushCommitToRemote(remoteURL, remoteBranch, commit, targetURL, targetBranch string) error {
+	r, err := git.Clone(memory.NewStorage(), nil, &git.CloneOptions{
+		RemoteName:    "remote-repo",
+		URL:           remoteURL,
+		ReferenceName: plumbing.ReferenceName(defaultLocalRef + "/" + remoteBranch),
+		SingleBranch:  true,
+		NoCheckout:    true,
 	})
 	if err != nil {
 		return fmt.Errorf("error cloning git repo: %w", err)
 	}
 
-	err = r.Storer.RemoveReference(plumbing.NewBranchReferenceName(branch))
+	hash := plumbing.NewHash(commit)
+
+	_, err = r.CommitObject(hash)
+	if err != nil {
+		return fmt.Errorf("commit %s is not contained in branch %s: %w", commit, remoteBranch, err)
+	}
+
+	local := plumbing.NewHashReference(plumbing.ReferenceName(defaultLocalRef+"/push-"+commit), hash)
+
+	err = r.Storer.SetReference(local)
+	if err != nil {
+		return fmt.Errorf("error creating reference: %w", err)
+	}
+
+	remote, err := r.CreateRemote(&config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{targetURL},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating remote: %w", err)
+	}
+
+	err = remote.Push(&git.PushOptions{
+		RemoteName: "origin",
+		RefSpecs: []config.RefSpec{
+			config.RefSpec(local.Name().String() + ":" + defaultLocalRef + "/" + targetBranch),
+		},
+		Force: true,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("error pushing to repo: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteBranch deletes the given branch in the remote repository. Deleting a branch that does not exist is not an error.
+func DeleteBranch(repoURL, branch string) error {
+	r, err := git.Init(memory.NewStorage(), nil)
+	if err != nil {
+		return fmt.Errorf("error initializing git repo: %w", err)
+	}
+
+	remote, err := r.CreateRemote(&config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{repoURL},
+	})
 	if err != nil {
-		return fmt.Errorf("error deleting branch in git repo: %w", err)
+		return fmt.Errorf("error creating remote: %w", err)
+	}
+
+	err = remote.Push(&git.PushOptions{
+		RemoteName: "origin",
+		RefSpecs: []config.RefSpec{
+			config.RefSpec(":" + defaultLocalRef + "/" + branch),
+		},
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("error deleting branch in remote repo: %w", err)
 	}
 
 	return nil
 }
 
-func CreateTag(repoURL, branch, tag, user string) error {
+func CreateTag(repoURL, branch, tag, user string, identity Identity) error {
 	r, err := git.Clone(memory.NewStorage(), memfs.New(), &git.CloneOptions{
 		URL:   repoURL,
 		Depth: 1,
@@ -150,13 +223,7 @@ func CreateTag(repoURL, branch, tag, user string) error {
 		return fmt.Errorf("error finding head: %w", err)
 	}
 
-	_, err = r.CreateTag(tag, head.Hash(), &git.CreateTagOptions{
-		Tagger: &object.Signature{
-			Name:  defaultAuthor,
-			Email: defaultAuthorMail,
-		},
-		Message: "Bumped through metal-robot by " + user,
-	})
+	err = createTag(r, tag, head.Hash(), identity, "Bumped through metal-robot by "+user)
 	if err != nil {
 		return fmt.Errorf("error creating tag: %w", err)
 	}
@@ -174,7 +241,7 @@ func CreateTag(repoURL, branch, tag, user string) error {
 	return nil
 }
 
-func CommitAndPush(r *git.Repository, msg string) (string, error) {
+func CommitAndPush(r *git.Repository, msg string, identity Identity) (string, error) {
 	w, err := r.Worktree()
 	if err != nil {
 		return "", fmt.Errorf("error getting worktree: %w", err)
@@ -195,17 +262,21 @@ func CommitAndPush(r *git.Repository, msg string) (string, error) {
 	}
 
 	hash, err := w.Commit(msg, &git.CommitOptions{
-		Author: &object.Signature{
-			Name:  defaultAuthor,
-			Email: defaultAuthorMail,
-			When:  time.Now(),
-		},
-		All: true,
+		Author:    identity.author(),
+		Committer: identity.committer(),
+		All:       true,
 	})
 	if err != nil {
 		return "", fmt.Errorf("error during git commit: %w", err)
 	}
 
+	if identity.Signer != nil {
+		hash, err = signCommit(r, hash, identity.Signer)
+		if err != nil {
+			return "", fmt.Errorf("error signing git commit: %w", err)
+		}
+	}
+
 	branch, err := GetCurrentBranchFromRepository(r)
 	if err != nil {
 		return "", fmt.Errorf("error finding current branch: %w", err)
@@ -217,12 +288,26 @@ func CommitAndPush(r *git.Repository, msg string) (string, error) {
 		},
 	})
 	if err != nil {
+		if isNonFastForward(err) {
+			return "", fmt.Errorf("error pushing to repo: %w: %w", ErrNonFastForward, err)
+		}
 		return "", fmt.Errorf("error pushing to repo: %w", err)
 	}
 
 	return hash.String(), nil
 }
 
+// isNonFastForward detects push rejections from go-git's local fast-forward check as well as from the remote
+func isNonFastForward(err error) bool {
+	if errors.Is(err, git.ErrNonFastForwardUpdate) {
+		return true
+	}
+
+	msg := err.Error()
+
+	return strings.Contains(msg, "non-fast-forward") || strings.Contains(msg, "fetch first")
+}
+
 func GetCurrentBranchFromRepository(r *git.Repository) (string, error) {
 	branchRefs, err := r.Branches()
 	if err != nil {
@@ -279,13 +364,14 @@ func WriteRepoFile(r *git.Repository, path string, data []byte) error {
 		return fmt.Errorf("error retrieving git worktree: %w", err)
 	}
 
-	f, err := w.Filesystem.Open(path)
-	if err != nil {
+	// files that do not exist yet are created
+	info, err := w.Filesystem.Stat(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		return fmt.Errorf("error opening repository file: %w", err)
 	}
-	defer func() {
-		_ = f.Close()
-	}()
+	if info != nil && info.IsDir() {
+		return fmt.Errorf("error opening repository file: %s is a directory", path)
+	}
 
 	err = util.WriteFile(w.Filesystem, path, data, 0755)
 	if err != nil {
@@ -294,3 +380,133 @@ func WriteRepoFile(r *git.Repository, path string, data []byte) error {
 
 	return nil
 }
+
+// ListRepoFiles returns the paths of all files below the given directory of the worktree, an empty directory lists the entire worktree.
+func ListRepoFiles(r *git.Repository, dir string) ([]string, error) {
+	w, err := r.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving git worktree: %w", err)
+	}
+
+	if dir == "" {
+		dir = "/"
+	}
+
+	var files []string
+
+	err = util.Walk(w.Filesystem, dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		files = append(files, strings.TrimPrefix(filepath.ToSlash(path), "/"))
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing repository files: %w", err)
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// ReadSubmoduleCommit returns the commit that the gitlink of the submodule at the given path points to.
+func ReadSubmoduleCommit(r *git.Repository, path string) (string, error) {
+	idx, err := r.Storer.Index()
+	if err != nil {
+		return "", fmt.Errorf("error reading git index: %w", err)
+	}
+
+	e, err := idx.Entry(path)
+	if err != nil {
+		return "", fmt.Errorf("submodule %s not found in git index: %w", path, err)
+	}
+
+	if e.Mode != filemode.Submodule {
+		return "", fmt.Errorf("%s is not a submodule", path)
+	}
+
+	return e.Hash.String(), nil
+}
+
+// WriteSubmoduleCommit points the gitlink of the submodule at the given path to the given commit.
+// The change is written to the index directly, such that the submodule does not need to be checked out.
+func WriteSubmoduleCommit(r *git.Repository, path, commit string) error {
+	if !plumbing.IsHash(commit) {
+		return fmt.Errorf("invalid commit hash: %s", commit)
+	}
+
+	idx, err := r.Storer.Index()
+	if err != nil {
+		return fmt.Errorf("error reading git index: %w", err)
+	}
+
+	e, err := idx.Entry(path)
+	if err != nil {
+		return fmt.Errorf("submodule %s not found in git index: %w", path, err)
+	}
+
+	if e.Mode != filemode.Submodule {
+		return fmt.Errorf("%s is not a submodule", path)
+	}
+
+	e.Hash = plumbing.NewHash(commit)
+
+	err = r.Storer.SetIndex(idx)
+	if err != nil {
+		return fmt.Errorf("error writing git index: %w", err)
+	}
+
+	return nil
+}
+
+// ResolveTag returns the hash of the commit that the given tag of the remote repository points to.
+func ResolveTag(repoURL, tag string) (string, error) {
+	ep, err := transport.NewEndpoint(repoURL)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse repository url: %w", err)
+	}
+
+	c, err := client.NewClient(ep)
+	if err != nil {
+		return "", fmt.Errorf("error creating git client: %w", err)
+	}
+
+	s, err := c.NewUploadPackSession(ep, nil)
+	if err != nil {
+		return "", fmt.Errorf("error connecting to repository: %w", err)
+	}
+	defer func() {
+		_ = s.Close()
+	}()
+
+	ar, err := s.AdvertisedReferences()
+	if err != nil {
+		return "", fmt.Errorf("error listing repository refs: %w", err)
+	}
+
+	name := plumbing.NewTagReferenceName(tag).String()
+
+	// annotated tags are advertised with the commit they point to as peeled reference
+	if hash, ok := ar.Peeled[name]; ok {
+		return hash.String(), nil
+	}
+
+	if hash, ok := ar.References[name]; ok {
+		return hash.String(), nil
+	}
+
+	return "", fmt.Errorf("tag %s not found in repository", tag)
+}
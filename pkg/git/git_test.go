@@ -0,0 +1,175 @@
+package git
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestDeleteBranch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("local git transport requires git binary")
+	}
+
+	upstream := t.TempDir()
+	upstreamRepo, err := git.PlainInit(upstream, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	worktree := t.TempDir()
+	worktreeRepo, err := git.PlainInit(worktree, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commitFile(t, worktreeRepo, worktree, "README.md", "fork build\n")
+
+	_, err = worktreeRepo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{upstream}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = worktreeRepo.Push(&git.PushOptions{
+		RemoteName: "origin",
+		RefSpecs: []config.RefSpec{
+			"refs/heads/master:refs/heads/master",
+			"refs/heads/master:refs/heads/fork-build/1",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = DeleteBranch(upstream, "fork-build/1")
+	if err != nil {
+		t.Fatalf("DeleteBranch() error = %v", err)
+	}
+
+	_, err = upstreamRepo.Reference(plumbing.NewBranchReferenceName("fork-build/1"), false)
+	if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		t.Errorf("expected branch to be deleted, got %v", err)
+	}
+
+	_, err = upstreamRepo.Reference(plumbing.Master, false)
+	if err != nil {
+		t.Errorf("expected master to be untouched, got %v", err)
+	}
+
+	err = DeleteBranch(upstream, "fork-build/1")
+	if err != nil {
+		t.Errorf("deleting a non-existing branch should not fail, got %v", err)
+	}
+}
+
+func TestPushCommitToRemote(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("local git transport requires git binary")
+	}
+
+	fork := t.TempDir()
+	forkRepo, err := git.PlainInit(fork, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commitFile(t, forkRepo, fork, "README.md", "verified\n")
+
+	verified, err := forkRepo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the contributor pushes again after the verified commit
+	commitFile(t, forkRepo, fork, "README.md", "unverified\n")
+
+	upstream := t.TempDir()
+	upstreamRepo, err := git.PlainInit(upstream, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = PushCommitToRemote(fork, "master", verified.Hash().String(), upstream, "fork-build/1")
+	if err != nil {
+		t.Fatalf("PushCommitToRemote() error = %v", err)
+	}
+
+	ref, err := upstreamRepo.Reference(plumbing.NewBranchReferenceName("fork-build/1"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref.Hash() != verified.Hash() {
+		t.Errorf("expected fork build branch to point to %s, got %s", verified.Hash(), ref.Hash())
+	}
+
+	err = PushCommitToRemote(fork, "master", "1111111111111111111111111111111111111111", upstream, "fork-build/1")
+	if err == nil {
+		t.Errorf("expected an error for a commit that is not contained in the branch")
+	}
+}
+
+func TestWriteSubmoduleCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("local git transport requires git binary")
+	}
+
+	run := func(dir string, args ...string) string {
+		cmd := exec.Command("git", append([]string{"-c", "protocol.file.allow=always", "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	sub := t.TempDir()
+	run(sub, "init", "-b", "master")
+	run(sub, "commit", "--allow-empty", "-m", "initial")
+	oldCommit := run(sub, "rev-parse", "HEAD")
+	run(sub, "commit", "--allow-empty", "-m", "release")
+	newCommit := run(sub, "rev-parse", "HEAD")
+
+	worktree := t.TempDir()
+	run(worktree, "init", "-b", "master")
+	run(worktree, "submodule", "add", sub, "vendor/sub")
+	run(worktree+"/vendor/sub", "checkout", oldCommit)
+	run(worktree, "add", "vendor/sub")
+	run(worktree, "commit", "-m", "add submodule")
+
+	upstream := t.TempDir()
+	run(upstream, "clone", "--bare", worktree, ".")
+
+	r, err := ShallowClone(upstream, "master", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadSubmoduleCommit(r, "vendor/sub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != oldCommit {
+		t.Errorf("ReadSubmoduleCommit() = %s, want %s", got, oldCommit)
+	}
+
+	err = WriteSubmoduleCommit(r, "vendor/sub", newCommit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = CommitAndPush(r, "bump submodule", Identity{})
+	if err != nil {
+		t.Fatalf("CommitAndPush() error = %v", err)
+	}
+
+	got = run(upstream, "rev-parse", "master:vendor/sub")
+	if got != newCommit {
+		t.Errorf("pushed submodule commit = %s, want %s", got, newCommit)
+	}
+}
@@ -0,0 +1,314 @@
+package git
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	sshSignatureNamespace = "git"
+	sshSignatureHashAlgo  = "sha512"
+)
+
+// Identity describes who authors, commits and signs the git objects that the robot creates.
+type Identity struct {
+	Author    object.Signature
+	Committer object.Signature
+	// Signer is optional, if set all commits and annotated tags are signed
+	Signer Signer
+}
+
+// Signer creates an armored detached signature over the given data as it is expected in
+// the gpgsig header of a commit or at the end of an annotated tag message.
+type Signer interface {
+	Sign(data []byte) (string, error)
+}
+
+// DefaultIdentity returns the identity that is used when nothing else was configured.
+func DefaultIdentity() Identity {
+	return Identity{
+		Author: object.Signature{
+			Name:  defaultAuthor,
+			Email: defaultAuthorMail,
+		},
+		Committer: object.Signature{
+			Name:  defaultAuthor,
+			Email: defaultAuthorMail,
+		},
+	}
+}
+
+func (i Identity) author() *object.Signature {
+	return &object.Signature{Name: i.Author.Name, Email: i.Author.Email, When: time.Now()}
+}
+
+func (i Identity) committer() *object.Signature {
+	return &object.Signature{Name: i.Committer.Name, Email: i.Committer.Email, When: time.Now()}
+}
+
+type openPGPSigner struct {
+	entity *openpgp.Entity
+}
+
+// NewOpenPGPSigner returns a signer for the given armored private key.
+func NewOpenPGPSigner(armoredKey []byte, passphrase []byte) (Signer, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(armoredKey))
+	if err != nil {
+		return nil, fmt.Errorf("error reading openpgp key: %w", err)
+	}
+
+	if len(entities) != 1 {
+		return nil, fmt.Errorf("expected exactly one openpgp key, got %d", len(entities))
+	}
+
+	entity := entities[0]
+
+	if entity.PrivateKey == nil {
+		return nil, fmt.Errorf("openpgp key does not contain a private key")
+	}
+
+	if entity.PrivateKey.Encrypted {
+		err = entity.PrivateKey.Decrypt(passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("error decrypting openpgp key: %w", err)
+		}
+	}
+
+	for _, subkey := range entity.Subkeys {
+		if subkey.PrivateKey != nil && subkey.PrivateKey.Encrypted {
+			err = subkey.PrivateKey.Decrypt(passphrase)
+			if err != nil {
+				return nil, fmt.Errorf("error decrypting openpgp subkey: %w", err)
+			}
+		}
+	}
+
+	return &openPGPSigner{entity: entity}, nil
+}
+
+func (s *openPGPSigner) Sign(data []byte) (string, error) {
+	var sig bytes.Buffer
+
+	err := openpgp.ArmoredDetachSign(&sig, s.entity, bytes.NewReader(data), &packet.Config{})
+	if err != nil {
+		return "", fmt.Errorf("error creating openpgp signature: %w", err)
+	}
+
+	return sig.String(), nil
+}
+
+type sshSigner struct {
+	signer ssh.Signer
+}
+
+// NewSSHSigner returns a signer for the given private key in openssh format.
+func NewSSHSigner(privateKey []byte, passphrase []byte) (Signer, error) {
+	var (
+		signer ssh.Signer
+		err    error
+	)
+
+	if len(passphrase) > 0 {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(privateKey, passphrase)
+	} else {
+		signer, err = ssh.ParsePrivateKey(privateKey)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading ssh key: %w", err)
+	}
+
+	return &sshSigner{signer: signer}, nil
+}
+
+// Sign creates a signature in the sshsig format, see https://github.com/openssh/openssh-portable/blob/master/PROTOCOL.sshsig
+func (s *sshSigner) Sign(data []byte) (string, error) {
+	hash := sha512.Sum512(data)
+
+	signedData := ssh.Marshal(struct {
+		Namespace string
+		Reserved  string
+		HashAlgo  string
+		Hash      string
+	}{
+		Namespace: sshSignatureNamespace,
+		HashAlgo:  sshSignatureHashAlgo,
+		Hash:      string(hash[:]),
+	})
+	signedData = append([]byte("SSHSIG"), signedData...)
+
+	var (
+		sig *ssh.Signature
+		err error
+	)
+
+	if as, ok := s.signer.(ssh.AlgorithmSigner); ok && s.signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		sig, err = as.SignWithAlgorithm(nil, signedData, ssh.KeyAlgoRSASHA512)
+	} else {
+		sig, err = s.signer.Sign(nil, signedData)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error creating ssh signature: %w", err)
+	}
+
+	blob := ssh.Marshal(struct {
+		Version   uint32
+		PublicKey string
+		Namespace string
+		Reserved  string
+		HashAlgo  string
+		Signature string
+	}{
+		Version:   1,
+		PublicKey: string(s.signer.PublicKey().Marshal()),
+		Namespace: sshSignatureNamespace,
+		HashAlgo:  sshSignatureHashAlgo,
+		Signature: string(ssh.Marshal(sig)),
+	})
+	blob = append([]byte("SSHSIG"), blob...)
+
+	encoded := base64.StdEncoding.EncodeToString(blob)
+
+	var armored strings.Builder
+	armored.WriteString("-----BEGIN SSH SIGNATURE-----\n")
+	for len(encoded) > 70 {
+		armored.WriteString(encoded[:70] + "\n")
+		encoded = encoded[70:]
+	}
+	armored.WriteString(encoded + "\n")
+	armored.WriteString("-----END SSH SIGNATURE-----\n")
+
+	return armored.String(), nil
+}
+
+// signCommit replaces the given commit with a signed version and moves the current branch to it.
+func signCommit(r *git.Repository, hash plumbing.Hash, signer Signer) (plumbing.Hash, error) {
+	commit, err := r.CommitObject(hash)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("error finding commit: %w", err)
+	}
+
+	unsigned := r.Storer.NewEncodedObject()
+	err = commit.EncodeWithoutSignature(unsigned)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	data, err := readObject(unsigned)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	commit.PGPSignature, err = signer.Sign(data)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	signed := r.Storer.NewEncodedObject()
+	err = commit.Encode(signed)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	signedHash, err := r.Storer.SetEncodedObject(signed)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("error storing signed commit: %w", err)
+	}
+
+	head, err := r.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("error finding head: %w", err)
+	}
+
+	name := plumbing.HEAD
+	if head.Type() == plumbing.SymbolicReference {
+		name = head.Target()
+	}
+
+	err = r.Storer.SetReference(plumbing.NewHashReference(name, signedHash))
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("error updating branch to signed commit: %w", err)
+	}
+
+	return signedHash, nil
+}
+
+// createTag creates an annotated tag, which is signed in case the identity contains a signer.
+func createTag(r *git.Repository, name string, target plumbing.Hash, identity Identity, msg string) error {
+	if !strings.HasSuffix(msg, "\n") {
+		msg += "\n"
+	}
+
+	tag := &object.Tag{
+		Name:       name,
+		Tagger:     *identity.committer(),
+		Message:    msg,
+		TargetType: plumbing.CommitObject,
+		Target:     target,
+	}
+
+	if identity.Signer != nil {
+		unsigned := r.Storer.NewEncodedObject()
+		err := tag.EncodeWithoutSignature(unsigned)
+		if err != nil {
+			return err
+		}
+
+		data, err := readObject(unsigned)
+		if err != nil {
+			return err
+		}
+
+		tag.PGPSignature, err = identity.Signer.Sign(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	obj := r.Storer.NewEncodedObject()
+	err := tag.Encode(obj)
+	if err != nil {
+		return err
+	}
+
+	hash, err := r.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return fmt.Errorf("error storing tag: %w", err)
+	}
+
+	ref := plumbing.NewHashReference(plumbing.NewTagReferenceName(name), hash)
+
+	_, err = r.Storer.Reference(ref.Name())
+	if err == nil {
+		return git.ErrTagExists
+	}
+	if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return err
+	}
+
+	return r.Storer.SetReference(ref)
+}
+
+func readObject(obj plumbing.EncodedObject) ([]byte, error) {
+	reader, err := obj.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	return io.ReadAll(reader)
+}
@@ -0,0 +1,238 @@
+package git
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSignCommitAndTag_OpenPGP(t *testing.T) {
+	entity, err := openpgp.NewEntity("metal-robot", "", "robot@metal-stack.io", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var private, public bytes.Buffer
+
+	w, err := armor.Encode(&private, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = entity.SerializePrivate(w, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = w.Close()
+
+	w, err = armor.Encode(&public, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = entity.Serialize(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = w.Close()
+
+	signer, err := NewOpenPGPSigner(private.Bytes(), nil)
+	if err != nil {
+		t.Fatalf("NewOpenPGPSigner() error = %v", err)
+	}
+
+	r, hash := testCommit(t)
+
+	signed, err := signCommit(r, hash, signer)
+	if err != nil {
+		t.Fatalf("signCommit() error = %v", err)
+	}
+
+	commit, err := r.CommitObject(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = commit.Verify(public.String())
+	if err != nil {
+		t.Errorf("commit signature could not be verified: %v", err)
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Hash() != signed {
+		t.Errorf("branch was not moved to signed commit")
+	}
+
+	identity := DefaultIdentity()
+	identity.Signer = signer
+
+	err = createTag(r, "v0.0.1", signed, identity, "release")
+	if err != nil {
+		t.Fatalf("createTag() error = %v", err)
+	}
+
+	tag, err := r.Tag("v0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tagObject, err := r.TagObject(tag.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = tagObject.Verify(public.String())
+	if err != nil {
+		t.Errorf("tag signature could not be verified: %v", err)
+	}
+}
+
+func TestSignCommit_SSH(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := NewSSHSigner(pem.EncodeToMemory(block), nil)
+	if err != nil {
+		t.Fatalf("NewSSHSigner() error = %v", err)
+	}
+
+	r, hash := testCommit(t)
+
+	signed, err := signCommit(r, hash, signer)
+	if err != nil {
+		t.Fatalf("signCommit() error = %v", err)
+	}
+
+	commit, err := r.CommitObject(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unsigned := r.Storer.NewEncodedObject()
+	err = commit.EncodeWithoutSignature(unsigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := readObject(unsigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifySSHSignature(t, sshPub, commit.PGPSignature, data)
+}
+
+// verifySSHSignature verifies a signature in the sshsig format like ssh-keygen -Y verify does
+func verifySSHSignature(t *testing.T, pub ssh.PublicKey, armored string, data []byte) {
+	armored = strings.TrimPrefix(armored, "-----BEGIN SSH SIGNATURE-----\n")
+	armored = strings.TrimSuffix(armored, "-----END SSH SIGNATURE-----\n")
+
+	blob, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(armored, "\n", ""))
+	if err != nil {
+		t.Fatalf("invalid signature encoding: %v", err)
+	}
+
+	if !bytes.HasPrefix(blob, []byte("SSHSIG")) {
+		t.Fatalf("signature is missing magic preamble")
+	}
+
+	var sig struct {
+		Version   uint32
+		PublicKey string
+		Namespace string
+		Reserved  string
+		HashAlgo  string
+		Signature string
+	}
+	err = ssh.Unmarshal(blob[6:], &sig)
+	if err != nil {
+		t.Fatalf("invalid signature blob: %v", err)
+	}
+
+	if sig.PublicKey != string(pub.Marshal()) {
+		t.Errorf("signature contains wrong public key")
+	}
+
+	var s ssh.Signature
+	err = ssh.Unmarshal([]byte(sig.Signature), &s)
+	if err != nil {
+		t.Fatalf("invalid signature: %v", err)
+	}
+
+	hash := sha512.Sum512(data)
+	signedData := append([]byte("SSHSIG"), ssh.Marshal(struct {
+		Namespace string
+		Reserved  string
+		HashAlgo  string
+		Hash      string
+	}{
+		Namespace: sig.Namespace,
+		HashAlgo:  sig.HashAlgo,
+		Hash:      string(hash[:]),
+	})...)
+
+	err = pub.Verify(signedData, &s)
+	if err != nil {
+		t.Errorf("ssh signature could not be verified: %v", err)
+	}
+}
+
+func testCommit(t *testing.T) (*git.Repository, plumbing.Hash) {
+	r, err := git.Init(memory.NewStorage(), memfs.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = util.WriteFile(w.Filesystem, "release.yaml", []byte("version: v0.0.1\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = w.Add("release.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := w.Commit("initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: defaultAuthor, Email: defaultAuthorMail, When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return r, hash
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// TemplateData is passed to the templates of the release actions and modifiers.
+type TemplateData struct {
+	// Value is the value that is patched, which is the tag unless the value is taken from the released repository
+	Value string `json:"value"`
+	// Tag is the release tag
+	Tag string `json:"tag"`
+	// Version is the release tag without the leading v
+	Version    string `json:"version"`
+	Major      uint64 `json:"major"`
+	Minor      uint64 `json:"minor"`
+	Patch      uint64 `json:"patch"`
+	Prerelease string `json:"prerelease"`
+	// RepositoryName is the name of the released repository
+	RepositoryName string `json:"repository-name"`
+	// RepositoryURL is the url of the released repository
+	RepositoryURL string `json:"repository-url"`
+	// Sender is the user who triggered the release
+	Sender string `json:"sender"`
+	// OldValue is the value that is replaced, it is only available in modifier templates
+	OldValue string `json:"old-value,omitempty"`
+}
+
+// NewTemplateData returns the template data for the given release tag, the version fields are only set for semantic versions.
+func NewTemplateData(tag string) TemplateData {
+	data := TemplateData{
+		Value:   tag,
+		Tag:     tag,
+		Version: strings.TrimPrefix(tag, "v"),
+	}
+
+	if v, err := semver.NewVersion(data.Version); err == nil {
+		data.Major = v.Major()
+		data.Minor = v.Minor()
+		data.Patch = v.Patch()
+		data.Prerelease = v.Prerelease()
+	}
+
+	return data
+}
+
+// RenderTemplate renders a go text template with the given data. Templates without any actions are formatted with fmt.Sprintf
+// and the given arguments instead, such that existing templates with %s verbs keep working.
+func RenderTemplate(tpl string, data TemplateData, args ...any) (string, error) {
+	if !IsTemplate(tpl) {
+		return fmt.Sprintf(tpl, args...), nil
+	}
+
+	t, err := template.New("").Parse(tpl)
+	if err != nil {
+		return "", fmt.Errorf("error parsing template: %w", err)
+	}
+
+	var res strings.Builder
+	err = t.Execute(&res, data)
+	if err != nil {
+		return "", fmt.Errorf("error rendering template: %w", err)
+	}
+
+	return res.String(), nil
+}
+
+// ValidateTemplate checks that the given template can be parsed and rendered, such that unknown fields and invalid
+// function calls are detected on startup and not only on the next release.
+func ValidateTemplate(tpl string) error {
+	if !IsTemplate(tpl) {
+		return nil
+	}
+
+	t, err := template.New("").Parse(tpl)
+	if err != nil {
+		return fmt.Errorf("error parsing template: %w", err)
+	}
+
+	err = t.Execute(io.Discard, TemplateData{})
+	if err != nil {
+		return fmt.Errorf("error rendering template: %w", err)
+	}
+
+	return nil
+}
+
+// IsTemplate returns true if the given string is a go text template and not a format string.
+func IsTemplate(tpl string) bool {
+	return strings.Contains(tpl, "{{")
+}
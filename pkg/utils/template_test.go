@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	data := NewTemplateData("v1.2.3-rc.1")
+	data.RepositoryName = "metal-api"
+	data.OldValue = "v1.2.2"
+
+	tests := []struct {
+		name    string
+		tpl     string
+		args    []any
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "format string",
+			tpl:  "Bump %s to version %s",
+			args: []any{"metal-api", "v1.2.3-rc.1"},
+			want: "Bump metal-api to version v1.2.3-rc.1",
+		},
+		{
+			name: "go template",
+			tpl:  "{{ .RepositoryName }}: {{ .OldValue }} -> {{ .Version }} ({{ .Major }}.{{ .Minor }}.{{ .Patch }} {{ .Prerelease }})",
+			want: "metal-api: v1.2.2 -> 1.2.3-rc.1 (1.2.3 rc.1)",
+		},
+		{
+			name:    "unknown field",
+			tpl:     "{{ .Unknown }}",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderTemplate(tt.tpl, data, tt.args...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RenderTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("RenderTemplate() diff: %v", diff)
+			}
+		})
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		tpl     string
+		wantErr bool
+	}{
+		{
+			name: "format string",
+			tpl:  "Bump %s to version %s",
+		},
+		{
+			name: "go template",
+			tpl:  "{{ .RepositoryName }}: {{ .OldValue }} -> {{ .Version }} ({{ .Major }}.{{ .Minor }}.{{ .Patch }} {{ .Prerelease }})",
+		},
+		{
+			name:    "invalid syntax",
+			tpl:     "{{ .Version ",
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			tpl:     "{{ .Unknown }}",
+			wantErr: true,
+		},
+		{
+			name:    "invalid function call",
+			tpl:     "{{ len .Major }}",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateTemplate(tt.tpl); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/metal-stack/metal-robot/pkg/config"
+)
+
+const (
+	IncrementMajor = "major"
+	IncrementMinor = "minor"
+	IncrementPatch = "patch"
+
+	// PrereleaseSkip never updates to prereleases
+	PrereleaseSkip = "skip"
+	// PrereleaseAllow updates to releases and prereleases
+	PrereleaseAllow = "allow"
+	// PrereleaseOnly only updates to prereleases
+	PrereleaseOnly = "only"
+)
+
+// VersionPolicy decides which versions are updated. A nil policy allows all versions.
+type VersionPolicy struct {
+	increments []string
+	prerelease string
+	constraint *semver.Constraints
+	pinned     bool
+}
+
+// NewVersionPolicy returns the policy for the given configuration, the prerelease handling defaults to the given value.
+// In case no configuration is given, a policy is only returned if the prerelease default is not to allow all versions.
+func NewVersionPolicy(c *config.VersionPolicyConfig, defaultPrerelease string) (*VersionPolicy, error) {
+	if c == nil {
+		if defaultPrerelease == PrereleaseAllow {
+			return nil, nil
+		}
+		c = &config.VersionPolicyConfig{}
+	}
+
+	p := &VersionPolicy{
+		increments: c.Increments,
+		prerelease: defaultPrerelease,
+		pinned:     c.Pinned,
+	}
+
+	if c.Prerelease != nil {
+		p.prerelease = *c.Prerelease
+	}
+
+	switch p.prerelease {
+	case PrereleaseSkip, PrereleaseAllow, PrereleaseOnly:
+	default:
+		return nil, fmt.Errorf("unsupported prerelease handling: %s", p.prerelease)
+	}
+
+	for _, i := range p.increments {
+		switch i {
+		case IncrementMajor, IncrementMinor, IncrementPatch:
+		default:
+			return nil, fmt.Errorf("unsupported version increment: %s", i)
+		}
+	}
+
+	if c.Constraint != nil {
+		constraint, err := semver.NewConstraint(*c.Constraint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version constraint: %w", err)
+		}
+		p.constraint = constraint
+	}
+
+	return p, nil
+}
+
+// Allows returns nil if the policy allows updating from the current to the new version, otherwise the reason is returned.
+// The current version may be nil if it is unknown, then the allowed increments are not checked.
+func (p *VersionPolicy) Allows(current, newVersion *semver.Version) error {
+	if p == nil {
+		return nil
+	}
+
+	if p.pinned {
+		return fmt.Errorf("version is pinned")
+	}
+
+	isPrerelease := newVersion.Prerelease() != ""
+	switch {
+	case p.prerelease == PrereleaseSkip && isPrerelease:
+		return fmt.Errorf("%s is a prerelease", newVersion.Original())
+	case p.prerelease == PrereleaseOnly && !isPrerelease:
+		return fmt.Errorf("%s is not a prerelease", newVersion.Original())
+	}
+
+	if p.constraint != nil {
+		// constraints do not match prereleases unless the constraint contains a prerelease, so they are compared without it
+		candidate := newVersion
+		if isPrerelease {
+			candidate = semver.New(newVersion.Major(), newVersion.Minor(), newVersion.Patch(), "", "")
+		}
+
+		if !p.constraint.Check(candidate) {
+			return fmt.Errorf("%s does not satisfy constraint %s", newVersion.Original(), p.constraint.String())
+		}
+	}
+
+	if current != nil && len(p.increments) > 0 {
+		increment := IncrementPatch
+		switch {
+		case newVersion.Major() != current.Major():
+			increment = IncrementMajor
+		case newVersion.Minor() != current.Minor():
+			increment = IncrementMinor
+		}
+
+		if !slices.Contains(p.increments, increment) {
+			return fmt.Errorf("%s increment from %s to %s is not allowed", increment, current.Original(), newVersion.Original())
+		}
+	}
+
+	return nil
+}
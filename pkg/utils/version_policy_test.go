@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/metal-stack/metal-robot/pkg/config"
+)
+
+func TestVersionPolicy_Allows(t *testing.T) {
+	tests := []struct {
+		name              string
+		config            *config.VersionPolicyConfig
+		defaultPrerelease string
+		current           string
+		newVersion        string
+		wantAllowed       bool
+	}{
+		{
+			name:              "no policy allows everything",
+			defaultPrerelease: PrereleaseAllow,
+			current:           "1.4.2",
+			newVersion:        "2.0.0-rc.1",
+			wantAllowed:       true,
+		},
+		{
+			name:              "prereleases are skipped by default",
+			defaultPrerelease: PrereleaseSkip,
+			newVersion:        "2.0.0-rc.1",
+			wantAllowed:       false,
+		},
+		{
+			name:              "only prereleases",
+			config:            &config.VersionPolicyConfig{Prerelease: new(PrereleaseOnly)},
+			defaultPrerelease: PrereleaseSkip,
+			newVersion:        "2.0.0",
+			wantAllowed:       false,
+		},
+		{
+			name:              "patch increments only",
+			config:            &config.VersionPolicyConfig{Increments: []string{IncrementPatch}},
+			defaultPrerelease: PrereleaseAllow,
+			current:           "1.4.2",
+			newVersion:        "1.5.0",
+			wantAllowed:       false,
+		},
+		{
+			name:              "constraint matches maintenance line",
+			config:            &config.VersionPolicyConfig{Constraint: new("~1.4")},
+			defaultPrerelease: PrereleaseAllow,
+			current:           "1.4.2",
+			newVersion:        "1.4.3",
+			wantAllowed:       true,
+		},
+		{
+			name:              "constraint rejects other lines",
+			config:            &config.VersionPolicyConfig{Constraint: new("~1.4")},
+			defaultPrerelease: PrereleaseAllow,
+			current:           "1.4.2",
+			newVersion:        "1.5.0",
+			wantAllowed:       false,
+		},
+		{
+			name:              "constraint applies to prereleases of the line",
+			config:            &config.VersionPolicyConfig{Constraint: new("~1.4")},
+			defaultPrerelease: PrereleaseAllow,
+			newVersion:        "1.4.3-rc.1",
+			wantAllowed:       true,
+		},
+		{
+			name:              "pinned",
+			config:            &config.VersionPolicyConfig{Pinned: true},
+			defaultPrerelease: PrereleaseAllow,
+			current:           "1.4.2",
+			newVersion:        "1.4.3",
+			wantAllowed:       false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewVersionPolicy(tt.config, tt.defaultPrerelease)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var current *semver.Version
+			if tt.current != "" {
+				current = semver.MustParse(tt.current)
+			}
+
+			err = p.Allows(current, semver.MustParse(tt.newVersion))
+			if (err == nil) != tt.wantAllowed {
+				t.Errorf("VersionPolicy.Allows() error = %v, wantAllowed %v", err, tt.wantAllowed)
+			}
+		})
+	}
+}
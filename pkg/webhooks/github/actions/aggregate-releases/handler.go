@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"net/url"
 	"strings"
 	"sync"
 
@@ -16,6 +15,7 @@ import (
 	"github.com/metal-stack/metal-robot/pkg/clients"
 	"github.com/metal-stack/metal-robot/pkg/config"
 	"github.com/metal-stack/metal-robot/pkg/git"
+	"github.com/metal-stack/metal-robot/pkg/utils"
 	"github.com/metal-stack/metal-robot/pkg/webhooks/github/actions/common"
 	"github.com/metal-stack/metal-robot/pkg/webhooks/handlers"
 	handlerrors "github.com/metal-stack/metal-robot/pkg/webhooks/handlers/errors"
@@ -32,6 +32,8 @@ type aggregateReleases struct {
 	repoURL               string
 	repoName              string
 	pullRequestTitle      string
+	commitBackend         config.CommitBackend
+	dryRun                bool
 
 	lock *multilock.Lock
 }
@@ -43,7 +45,7 @@ type Params struct {
 	Sender         string
 }
 
-func New(client *clients.Github, rawConfig map[string]any) (handlers.WebhookHandler[*Params], error) {
+func New(client *clients.Github, rawConfig map[string]any, patcherOpts filepatchers.Options) (handlers.WebhookHandler[*Params], error) {
 	var (
 		branch                = "develop"
 		branchBase            = "master"
@@ -72,14 +74,22 @@ func New(client *clients.Github, rawConfig map[string]any) (handlers.WebhookHand
 	if typedConfig.CommitMsgTemplate != nil {
 		commitMessageTemplate = *typedConfig.CommitMsgTemplate
 	}
+	if err := utils.ValidateTemplate(commitMessageTemplate); err != nil {
+		return nil, fmt.Errorf("invalid commit message template: %w", err)
+	}
 	if typedConfig.PullRequestTitle != nil && *typedConfig.PullRequestTitle != "" {
 		pullRequestTitle = *typedConfig.PullRequestTitle
 	}
 
+	commitBackend, err := common.ParseCommitBackend(typedConfig.CommitBackend)
+	if err != nil {
+		return nil, err
+	}
+
 	patchMap := make(map[string][]filepatchers.Patcher)
 	for n, actions := range typedConfig.SourceRepos {
 		for _, m := range actions.Modifiers {
-			patcher, err := filepatchers.InitPatcher(m)
+			patcher, err := filepatchers.InitPatcher(m, patcherOpts)
 			if err != nil {
 				return nil, err
 			}
@@ -102,6 +112,8 @@ func New(client *clients.Github, rawConfig map[string]any) (handlers.WebhookHand
 		repoURL:               typedConfig.TargetRepositoryURL,
 		repoName:              typedConfig.TargetRepositoryName,
 		pullRequestTitle:      pullRequestTitle,
+		commitBackend:         commitBackend,
+		dryRun:                typedConfig.DryRun,
 		lock:                  multilock.New(typedConfig.TargetRepositoryName),
 	}, nil
 }
@@ -125,6 +137,46 @@ func (r *aggregateReleases) Handle(ctx context.Context, log *slog.Logger, p *Par
 		return handlerrors.Skip("not adding to release vector because not a valid semver release tag: %w", err)
 	}
 
+	data := common.ReleaseTemplateData(p.RepositoryName, p.RepositoryURL, p.Sender, tag)
+
+	open := func() (git.Branch, error) {
+		return common.OpenBranch(ctx, r.client, r.commitBackend, r.repoName, r.repoURL, r.branch)
+	}
+
+	// assets are only downloaded once, also when the changes are applied again after a concurrent modification
+	ctx = filepatchers.WithChecksumCache(ctx)
+
+	var diff string
+	apply := func(branch git.Branch) error {
+		rec := filepatchers.NewRecorder(branch)
+		for _, patch := range patches {
+			err := filepatchers.Apply(ctx, log, patch, rec, tag, data)
+			if err != nil {
+				return fmt.Errorf("error applying release updates: %w", err)
+			}
+		}
+
+		changes, err := rec.Diff()
+		if err != nil {
+			return err
+		}
+
+		diff = changes
+
+		return nil
+	}
+
+	if r.dryRun {
+		err = common.DryRun(open, apply)
+		if err != nil {
+			return err
+		}
+
+		log.Info("dry run, skip push to target repository", "diff", diff)
+
+		return nil
+	}
+
 	openPR, err := common.FindOpenReleasePR(ctx, r.client.GetV3Client(), r.client.Organization(), r.repoName, r.branch, r.branchBase)
 	if err != nil {
 		return fmt.Errorf("unable to find open release pull requests: %w", err)
@@ -161,39 +213,13 @@ func (r *aggregateReleases) Handle(ctx context.Context, log *slog.Logger, p *Par
 	r.lock.Lock()
 	defer once.Do(func() { r.lock.Unlock() })
 
-	token, err := r.client.GitToken(ctx)
-	if err != nil {
-		return fmt.Errorf("error creating git token: %w", err)
-	}
-
-	repoURL, err := url.Parse(r.repoURL)
+	commitMessage, err := utils.RenderTemplate(r.commitMessageTemplate, data, p.RepositoryName, tag)
 	if err != nil {
-		return fmt.Errorf("unable to parse repository url: %w", err)
+		return fmt.Errorf("error rendering commit message: %w", err)
 	}
-	repoURL.User = url.UserPassword("x-access-token", token)
 
-	repository, err := git.ShallowClone(repoURL.String(), r.branch, 1)
-	if err != nil {
-		return fmt.Errorf("unable to shallow clone repository: %w", err)
-	}
-
-	reader := func(file string) ([]byte, error) {
-		return git.ReadRepoFile(repository, file)
-	}
-
-	writer := func(file string, content []byte) error {
-		return git.WriteRepoFile(repository, file, content)
-	}
-
-	for _, patch := range patches {
-		err = patch.Apply(reader, writer, tag)
-		if err != nil {
-			return fmt.Errorf("error applying release updates: %w", err)
-		}
-	}
-
-	commitMessage := fmt.Sprintf(r.commitMessageTemplate, p.RepositoryName, tag)
-	hash, err := git.CommitAndPush(repository, commitMessage)
+	pushed := false
+	hash, err := common.CommitWithRetry(ctx, log, open, apply, commitMessage)
 	if err != nil {
 		if errors.Is(err, git.ErrNoChanges) {
 			log.Debug("skip push to target repository because nothing changed")
@@ -204,17 +230,23 @@ func (r *aggregateReleases) Handle(ctx context.Context, log *slog.Logger, p *Par
 		log.Info("pushed to aggregate target repo", "branch", r.branch, "hash", hash)
 
 		once.Do(func() { r.lock.Unlock() })
+
+		pushed = true
 	}
 
 	if openPR != nil {
-		return nil
+		if !pushed {
+			return nil
+		}
+
+		return common.CommentDiff(ctx, r.client.GetV3Client(), r.client.Organization(), r.repoName, *openPR.Number, commitMessage, diff)
 	}
 
 	pr, _, err := r.client.GetV3Client().PullRequests.Create(ctx, r.client.Organization(), r.repoName, &github.NewPullRequest{
 		Title:               new("Next release"),
 		Head:                new(r.branch),
 		Base:                new(r.branchBase),
-		Body:                new(r.pullRequestTitle),
+		Body:                new(common.PullRequestBody(r.pullRequestTitle, diff)),
 		MaintainerCanModify: new(true),
 	})
 	if err != nil {
@@ -0,0 +1,102 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+
+	"github.com/metal-stack/metal-robot/pkg/clients"
+	"github.com/metal-stack/metal-robot/pkg/config"
+	"github.com/metal-stack/metal-robot/pkg/git"
+)
+
+const (
+	// commitRetries is the number of times changes are re-applied on a fresh branch after a push was rejected
+	commitRetries = 3
+)
+
+// ParseCommitBackend returns the configured commit backend, defaulting to git.
+func ParseCommitBackend(backend *string) (config.CommitBackend, error) {
+	if backend == nil || *backend == "" {
+		return config.CommitBackendGit, nil
+	}
+
+	switch b := config.CommitBackend(*backend); b {
+	case config.CommitBackendGit, config.CommitBackendAPI:
+		return b, nil
+	default:
+		return "", fmt.Errorf("unsupported commit backend %q, must be one of %s or %s", b, config.CommitBackendGit, config.CommitBackendAPI)
+	}
+}
+
+// OpenBranch opens a branch of a repository in the organization of the client. With the git backend the repository
+// is cloned, with the api backend files are read and committed through the github api.
+func OpenBranch(ctx context.Context, client *clients.Github, backend config.CommitBackend, repoName, repoURL, branch string) (git.Branch, error) {
+	switch backend {
+	case config.CommitBackendAPI:
+		return git.NewAPIBranch(ctx, client.GetV3Client(), client.GetGraphQLClient(), client.Organization(), repoName, branch)
+	default:
+		token, err := client.GitToken(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error creating git token: %w", err)
+		}
+
+		u, err := url.Parse(repoURL)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse repository url: %w", err)
+		}
+		u.User = url.UserPassword("x-access-token", token)
+
+		if cache := client.GitCache(); cache != nil {
+			r, err := cache.Clone(u.String(), branch)
+			if err != nil {
+				return nil, fmt.Errorf("unable to clone repository into cache: %w", err)
+			}
+
+			return git.NewRepositoryBranch(r, client.GitIdentity()), nil
+		}
+
+		r, err := git.ShallowClone(u.String(), branch, 1)
+		if err != nil {
+			return nil, fmt.Errorf("unable to shallow clone repository: %w", err)
+		}
+
+		return git.NewRepositoryBranch(r, client.GitIdentity()), nil
+	}
+}
+
+// CommitWithRetry opens a branch, applies changes to it and commits them. When the push is rejected because the branch
+// was modified concurrently, the branch is opened again and the changes are re-applied on top of the fresh state.
+func CommitWithRetry(ctx context.Context, log *slog.Logger, open func() (git.Branch, error), apply func(branch git.Branch) error, msg string) (string, error) {
+	for attempt := 1; ; attempt++ {
+		branch, err := open()
+		if err != nil {
+			return "", err
+		}
+
+		err = apply(branch)
+		if err != nil {
+			return "", err
+		}
+
+		hash, err := branch.CommitAndPush(ctx, msg)
+		if err != nil && errors.Is(err, git.ErrNonFastForward) && attempt <= commitRetries {
+			log.Info("push was rejected because branch was modified concurrently, re-applying changes on fresh branch", "attempt", attempt, "error", err)
+			continue
+		}
+
+		return hash, err
+	}
+}
+
+// DryRun opens a branch and applies changes to it without committing them.
+func DryRun(open func() (git.Branch, error), apply func(branch git.Branch) error) error {
+	branch, err := open()
+	if err != nil {
+		return err
+	}
+
+	return apply(branch)
+}
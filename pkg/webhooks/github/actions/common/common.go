@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/go-github/v79/github"
 	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/metal-stack/metal-robot/pkg/utils"
 )
 
 type CommentCommand string
@@ -135,3 +136,45 @@ func sortComments(comments []*github.IssueComment) func(i, j int) bool {
 		return comments[j].CreatedAt.Before(comments[i].CreatedAt.Time)
 	}
 }
+
+// ReleaseTemplateData returns the data for rendering templates on a release of the given repository.
+func ReleaseTemplateData(repositoryName, repositoryURL, sender, tag string) utils.TemplateData {
+	data := utils.NewTemplateData(tag)
+	data.RepositoryName = repositoryName
+	data.RepositoryURL = repositoryURL
+	data.Sender = sender
+	return data
+}
+
+// maxPullRequestDiffLength keeps pull request bodies below the size limit of github
+const maxPullRequestDiffLength = 60000
+
+// PullRequestBody appends the diff of the changes to the description of a pull request, long diffs are truncated.
+func PullRequestBody(description, diff string) string {
+	if diff == "" {
+		return description
+	}
+
+	if len(diff) > maxPullRequestDiffLength {
+		diff = diff[:strings.LastIndex(diff[:maxPullRequestDiffLength], "\n")+1] + "... (truncated)\n"
+	}
+
+	return description + "\n\n<details>\n<summary>Changes</summary>\n\n```diff\n" + diff + "```\n\n</details>\n"
+}
+
+// CommentDiff adds the diff of changes that were pushed to the branch of an already open pull request as a comment,
+// such that the changes of every push stay visible and the pull request body is left as it is.
+func CommentDiff(ctx context.Context, client *github.Client, owner, repo string, number int, description, diff string) error {
+	if diff == "" {
+		return nil
+	}
+
+	_, _, err := client.Issues.CreateComment(ctx, owner, repo, number, &github.IssueComment{
+		Body: new(PullRequestBody(description, diff)),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to comment changes on pull request: %w", err)
+	}
+
+	return nil
+}
@@ -1,7 +1,12 @@
 package common
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -110,3 +115,89 @@ func Test_sortComments(t *testing.T) {
 		})
 	}
 }
+
+func TestPullRequestBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		diff        string
+		want        string
+	}{
+		{
+			name:        "without changes",
+			description: "Bump version",
+			want:        "Bump version",
+		},
+		{
+			name:        "with changes",
+			description: "Bump version",
+			diff:        "--- a/release.yaml\n+++ b/release.yaml\n@@ -1 +1 @@\n-tag: v0.1.0\n+tag: v0.2.0\n",
+			want:        "Bump version\n\n<details>\n<summary>Changes</summary>\n\n```diff\n--- a/release.yaml\n+++ b/release.yaml\n@@ -1 +1 @@\n-tag: v0.1.0\n+tag: v0.2.0\n```\n\n</details>\n",
+		},
+		{
+			name:        "truncates long diffs at a line break",
+			description: "Bump version",
+			diff:        strings.Repeat("+"+strings.Repeat("a", 98)+"\n", maxPullRequestDiffLength/100+1),
+			want:        "Bump version\n\n<details>\n<summary>Changes</summary>\n\n```diff\n" + strings.Repeat("+"+strings.Repeat("a", 98)+"\n", maxPullRequestDiffLength/100) + "... (truncated)\n```\n\n</details>\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, PullRequestBody(tt.description, tt.diff)); diff != "" {
+				t.Errorf("PullRequestBody() diff: %v", diff)
+			}
+		})
+	}
+}
+
+func TestCommentDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+		want []string
+	}{
+		{
+			name: "without changes",
+		},
+		{
+			name: "with changes",
+			diff: "-tag: v0.1.0\n+tag: v0.2.0\n",
+			want: []string{"Bump version\n\n<details>\n<summary>Changes</summary>\n\n```diff\n-tag: v0.1.0\n+tag: v0.2.0\n```\n\n</details>\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != "/api/v3/repos/metal-stack/releases/issues/3/comments" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+
+				var comment github.IssueComment
+				if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
+					t.Error(err)
+				}
+				got = append(got, comment.GetBody())
+
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte(`{}`))
+			}))
+			defer server.Close()
+
+			client, err := github.NewClient(nil).WithEnterpriseURLs(server.URL, server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := CommentDiff(context.Background(), client, "metal-stack", "releases", 3, "Bump version", tt.diff); err != nil {
+				t.Errorf("CommentDiff() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("CommentDiff() diff: %v", diff)
+			}
+		})
+	}
+}
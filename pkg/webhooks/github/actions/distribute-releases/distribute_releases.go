@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"net/url"
 	"strings"
 	"sync"
 
@@ -16,6 +15,8 @@ import (
 	"github.com/metal-stack/metal-robot/pkg/clients"
 	"github.com/metal-stack/metal-robot/pkg/config"
 	"github.com/metal-stack/metal-robot/pkg/git"
+	"github.com/metal-stack/metal-robot/pkg/utils"
+	"github.com/metal-stack/metal-robot/pkg/webhooks/github/actions/common"
 	"github.com/metal-stack/metal-robot/pkg/webhooks/handlers"
 	handlerrors "github.com/metal-stack/metal-robot/pkg/webhooks/handlers/errors"
 	filepatchers "github.com/metal-stack/metal-robot/pkg/webhooks/modifiers/file-patchers"
@@ -25,7 +26,9 @@ import (
 
 type Params struct {
 	RepositoryName string
+	RepositoryURL  string
 	TagName        string
+	Sender         string
 }
 
 type distributeReleases struct {
@@ -36,15 +39,18 @@ type distributeReleases struct {
 	repoName              string
 	targetRepos           map[string]targetRepo
 	pullRequestTitle      string
+	commitBackend         config.CommitBackend
+	dryRun                bool
 }
 
 type targetRepo struct {
 	patches []filepatchers.Patcher
 	branch  string
 	url     string
+	policy  *utils.VersionPolicy
 }
 
-func New(client *clients.Github, rawConfig map[string]any) (handlers.WebhookHandler[*Params], error) {
+func New(client *clients.Github, rawConfig map[string]any, patcherOpts filepatchers.Options) (handlers.WebhookHandler[*Params], error) {
 	var (
 		commitMessageTemplate = "Bump %s to version %s"
 		branchTemplate        = "auto-generate/%s"
@@ -69,15 +75,26 @@ func New(client *clients.Github, rawConfig map[string]any) (handlers.WebhookHand
 	if typedConfig.CommitMsgTemplate != nil {
 		commitMessageTemplate = *typedConfig.CommitMsgTemplate
 	}
+	if err := utils.ValidateTemplate(branchTemplate); err != nil {
+		return nil, fmt.Errorf("invalid branch template: %w", err)
+	}
+	if err := utils.ValidateTemplate(commitMessageTemplate); err != nil {
+		return nil, fmt.Errorf("invalid commit message template: %w", err)
+	}
 	if typedConfig.PullRequestTitle != nil && *typedConfig.PullRequestTitle != "" {
 		pullRequestTitle = *typedConfig.PullRequestTitle
 	}
 
+	commitBackend, err := common.ParseCommitBackend(typedConfig.CommitBackend)
+	if err != nil {
+		return nil, err
+	}
+
 	targetRepos := make(map[string]targetRepo)
 	for _, t := range typedConfig.TargetRepos {
 		patches := []filepatchers.Patcher{}
 		for _, m := range t.Patches {
-			patcher, err := filepatchers.InitPatcher(m)
+			patcher, err := filepatchers.InitPatcher(m, patcherOpts)
 			if err != nil {
 				return nil, err
 			}
@@ -85,6 +102,16 @@ func New(client *clients.Github, rawConfig map[string]any) (handlers.WebhookHand
 			patches = append(patches, patcher)
 		}
 
+		// there is no current version of a target repository to compare with, so increments cannot be checked
+		if t.VersionPolicy != nil && len(t.VersionPolicy.Increments) > 0 {
+			return nil, fmt.Errorf("invalid version policy for target repository %s: increments are not supported, use a constraint instead", t.RepositoryName)
+		}
+
+		policy, err := utils.NewVersionPolicy(t.VersionPolicy, utils.PrereleaseSkip)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version policy for target repository %s: %w", t.RepositoryName, err)
+		}
+
 		branch := "master"
 		if t.Branch != "" {
 			branch = t.Branch
@@ -94,6 +121,7 @@ func New(client *clients.Github, rawConfig map[string]any) (handlers.WebhookHand
 			url:     t.RepositoryURL,
 			branch:  branch,
 			patches: patches,
+			policy:  policy,
 		}
 	}
 
@@ -105,6 +133,8 @@ func New(client *clients.Github, rawConfig map[string]any) (handlers.WebhookHand
 		repoName:              typedConfig.SourceRepositoryName,
 		targetRepos:           targetRepos,
 		pullRequestTitle:      pullRequestTitle,
+		commitBackend:         commitBackend,
+		dryRun:                typedConfig.DryRun,
 	}, nil
 }
 
@@ -126,13 +156,16 @@ func (d *distributeReleases) Handle(ctx context.Context, log *slog.Logger, p *Pa
 		return handlerrors.Skip("not adding to release vector because not a valid semver release tag: %w", err)
 	}
 
-	if parsedVersion.Prerelease() != "" {
-		return handlerrors.Skip("skip distribute release action because is a pre-release")
+	data := common.ReleaseTemplateData(p.RepositoryName, p.RepositoryURL, p.Sender, tag)
+
+	prBranch, err := utils.RenderTemplate(d.branchTemplate, data, tag)
+	if err != nil {
+		return fmt.Errorf("error rendering branch name: %w", err)
 	}
 
-	token, err := d.client.GitToken(ctx)
+	commitMessage, err := utils.RenderTemplate(d.commitMessageTemplate, data, p.RepositoryName, tag)
 	if err != nil {
-		return fmt.Errorf("error creating git token: %w", err)
+		return fmt.Errorf("error rendering commit message: %w", err)
 	}
 
 	var targetRepos []string
@@ -141,49 +174,63 @@ func (d *distributeReleases) Handle(ctx context.Context, log *slog.Logger, p *Pa
 	}
 	lock := multilock.New(targetRepos...)
 
+	// assets are only downloaded once for all target repos
+	ctx = filepatchers.WithChecksumCache(ctx)
+
 	g, _ := errgroup.WithContext(ctx)
 
 	for targetRepoName, targetRepo := range d.targetRepos {
 		g.Go(func() error {
-			log = log.With("target-repo", targetRepoName)
+			log := log.With("target-repo", targetRepoName)
+
+			if err := targetRepo.policy.Allows(nil, parsedVersion); err != nil {
+				log.Info("skip distributing release to target repo because of version policy", "reason", err)
+				return nil
+			}
 
 			log.Info("applying patch actions")
 
-			repoURL, err := url.Parse(targetRepo.url)
-			if err != nil {
-				return fmt.Errorf("unable to parse repository url: %w", err)
+			open := func() (git.Branch, error) {
+				return common.OpenBranch(ctx, d.client, d.commitBackend, targetRepoName, targetRepo.url, prBranch)
 			}
-			repoURL.User = url.UserPassword("x-access-token", token)
-
-			prBranch := fmt.Sprintf(d.branchTemplate, tag)
 
-			// preventing concurrent git repo modifications
-			var once sync.Once
-			lock.Lock()
-			defer once.Do(func() { lock.Unlock() })
+			var diff string
+			apply := func(branch git.Branch) error {
+				rec := filepatchers.NewRecorder(branch)
+				for _, patch := range targetRepo.patches {
+					err := filepatchers.Apply(ctx, log, patch, rec, tag, data)
+					if err != nil {
+						return fmt.Errorf("error applying repo updates: %w", err)
+					}
+				}
 
-			r, err := git.ShallowClone(repoURL.String(), prBranch, 1)
-			if err != nil {
-				return fmt.Errorf("unable to shallow clone repository: %w", err)
-			}
+				changes, err := rec.Diff()
+				if err != nil {
+					return err
+				}
 
-			reader := func(file string) ([]byte, error) {
-				return git.ReadRepoFile(r, file)
-			}
+				diff = changes
 
-			writer := func(file string, content []byte) error {
-				return git.WriteRepoFile(r, file, content)
+				return nil
 			}
 
-			for _, patch := range targetRepo.patches {
-				err = patch.Apply(reader, writer, tag)
+			if d.dryRun {
+				err := common.DryRun(open, apply)
 				if err != nil {
-					return fmt.Errorf("error applying repo updates: %w", err)
+					return fmt.Errorf("error applying release updates %w", err)
 				}
+
+				log.Info("dry run, skip pushing to target repo", "diff", diff)
+
+				return nil
 			}
 
-			commitMessage := fmt.Sprintf(d.commitMessageTemplate, p.RepositoryName, tag)
-			hash, err := git.CommitAndPush(r, commitMessage)
+			// preventing concurrent git repo modifications
+			var once sync.Once
+			lock.Lock()
+			defer once.Do(func() { lock.Unlock() })
+
+			hash, err := common.CommitWithRetry(ctx, log, open, apply, commitMessage)
 			if err != nil {
 				if errors.Is(err, git.ErrNoChanges) {
 					log.Debug("skip pushing to target repo because nothing changed")
@@ -196,11 +243,20 @@ func (d *distributeReleases) Handle(ctx context.Context, log *slog.Logger, p *Pa
 
 			once.Do(func() { lock.Unlock() })
 
+			openPR, err := common.FindOpenReleasePR(ctx, d.client.GetV3Client(), d.client.Organization(), targetRepoName, prBranch, targetRepo.branch)
+			if err != nil {
+				return err
+			}
+
+			if openPR != nil {
+				return common.CommentDiff(ctx, d.client.GetV3Client(), d.client.Organization(), targetRepoName, *openPR.Number, commitMessage, diff)
+			}
+
 			pr, _, err := d.client.GetV3Client().PullRequests.Create(ctx, d.client.Organization(), targetRepoName, &github.NewPullRequest{
 				Title:               new(commitMessage),
 				Head:                new(prBranch),
 				Base:                new(targetRepo.branch),
-				Body:                new(d.pullRequestTitle),
+				Body:                new(common.PullRequestBody(d.pullRequestTitle, diff)),
 				MaintainerCanModify: new(true),
 			})
 			if err != nil {
@@ -219,5 +275,8 @@ func (d *distributeReleases) Handle(ctx context.Context, log *slog.Logger, p *Pa
 		log.Error("errors occurred while applying release actions to target repos", "error", err)
 	}
 
+	rateLimit := d.client.RateLimit()
+	log.Debug("remaining github rate limit after distributing release", "remaining", rateLimit.Remaining, "limit", rateLimit.Limit, "reset", rateLimit.Reset)
+
 	return nil
 }
@@ -0,0 +1,131 @@
+package issue_comments
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strconv"
+
+	"github.com/google/go-github/v79/github"
+	"github.com/metal-stack/metal-robot/pkg/clients"
+)
+
+const (
+	forkBuildBranchPrefix = "fork-build/"
+	forkBuildTitlePrefix  = "Fork build for #"
+)
+
+var (
+	// the author of the fork pull request at the time of approval is stored in the body of the fork build pull request,
+	// such that synchronizing the fork build can be revoked when the author changes
+	approvedAuthorRegex = regexp.MustCompile(`<!-- fork-build-approved-author: (\S+) -->`)
+)
+
+type (
+	// git operations of the fork build handlers, they can be replaced for testing purposes
+	pushCommitFn   func(remoteURL, remoteBranch, commit, targetURL, targetBranch string) error
+	deleteBranchFn func(repoURL, branch string) error
+)
+
+func forkBuildBody(prNumber, approver, author string) string {
+	return forkBuildTitlePrefix + prNumber + " triggered by @" + approver + "\n\n<!-- fork-build-approved-author: " + author + " -->"
+}
+
+func approvedAuthor(body string) string {
+	matches := approvedAuthorRegex.FindStringSubmatch(body)
+	if len(matches) != 2 {
+		return ""
+	}
+	return matches[1]
+}
+
+// isMaintainer returns whether the given user has write permissions on the repository along with the actual permission
+func isMaintainer(ctx context.Context, client *clients.Github, repoName, user string) (bool, string, error) {
+	level, _, err := client.GetV3Client().Repositories.GetPermissionLevel(ctx, client.Organization(), repoName, user)
+	if err != nil {
+		return false, "", fmt.Errorf("error determining collaborator status: %w", err)
+	}
+
+	switch perm := level.GetPermission(); perm {
+	case "admin", "write":
+		return true, perm, nil
+	default:
+		return false, perm, nil
+	}
+}
+
+func authenticatedRepoURL(ctx context.Context, client *clients.Github, repoURL string) (string, error) {
+	token, err := client.GitToken(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error creating git token: %w", err)
+	}
+
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse repository url: %w", err)
+	}
+	u.User = url.UserPassword("x-access-token", token)
+
+	return u.String(), nil
+}
+
+// findForkBuildPullRequest returns the open fork build pull request for the given fork pull request or nil if there is none
+func findForkBuildPullRequest(ctx context.Context, client *clients.Github, repoName string, prNumber int) (*github.PullRequest, error) {
+	var (
+		number          = strconv.Itoa(prNumber)
+		forkBuildBranch = forkBuildBranchPrefix + number
+	)
+
+	prs, _, err := client.GetV3Client().PullRequests.List(ctx, client.Organization(), repoName, &github.PullRequestListOptions{
+		State: "open",
+		Head:  client.Organization() + ":" + forkBuildBranch,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing fork build pull requests: %w", err)
+	}
+
+	for _, pr := range prs {
+		if pr.GetTitle() == forkBuildTitlePrefix+number {
+			return pr, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// closeForkBuild closes the fork build pull request and deletes the fork build branch
+func closeForkBuild(ctx context.Context, log *slog.Logger, client *clients.Github, deleteBranch deleteBranchFn, repoName, repoURL string, prNumber int) error {
+	forkBuildBranch := forkBuildBranchPrefix + strconv.Itoa(prNumber)
+
+	forkPr, err := findForkBuildPullRequest(ctx, client, repoName, prNumber)
+	if err != nil {
+		return err
+	}
+
+	if forkPr != nil {
+		_, _, err = client.GetV3Client().PullRequests.Edit(ctx, client.Organization(), repoName, forkPr.GetNumber(), &github.PullRequest{
+			State: new("closed"),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to close fork build pull request: %w", err)
+		}
+
+		log.Info("closed fork build pull request", "pull-request-url", forkPr.GetHTMLURL())
+	}
+
+	targetRepoURL, err := authenticatedRepoURL(ctx, client, repoURL)
+	if err != nil {
+		return err
+	}
+
+	err = deleteBranch(targetRepoURL, forkBuildBranch)
+	if err != nil {
+		return fmt.Errorf("unable to delete fork build branch: %w", err)
+	}
+
+	log.Info("deleted fork build branch", "branch", forkBuildBranch)
+
+	return nil
+}
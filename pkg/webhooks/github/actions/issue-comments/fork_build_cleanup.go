@@ -0,0 +1,66 @@
+package issue_comments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/google/go-github/v79/github"
+	"github.com/metal-stack/metal-robot/pkg/clients"
+	"github.com/metal-stack/metal-robot/pkg/config"
+	"github.com/metal-stack/metal-robot/pkg/git"
+	"github.com/metal-stack/metal-robot/pkg/webhooks/handlers"
+	handlerrors "github.com/metal-stack/metal-robot/pkg/webhooks/handlers/errors"
+	"github.com/mitchellh/mapstructure"
+)
+
+type forkBuildCleanup struct {
+	client       *clients.Github
+	deleteBranch deleteBranchFn
+}
+
+type ForkBuildCleanupParams struct {
+	RepositoryName    string
+	RepositoryURL     string
+	PullRequestNumber int
+	Fork              bool
+}
+
+func NewForkBuildCleanup(client *clients.Github, rawConfig map[string]any) (handlers.WebhookHandler[*ForkBuildCleanupParams], error) {
+	var typedConfig config.IssueCommentsHandlerConfig
+	err := mapstructure.Decode(rawConfig, &typedConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &forkBuildCleanup{
+		client:       client,
+		deleteBranch: git.DeleteBranch,
+	}, nil
+}
+
+// Handle closes the fork build pull request and deletes the fork build branch after the original fork pull request was closed or merged
+func (r *forkBuildCleanup) Handle(ctx context.Context, log *slog.Logger, p *ForkBuildCleanupParams) error {
+	if !p.Fork {
+		return handlerrors.Skip("skip fork build cleanup, pull request is not from a fork")
+	}
+
+	var (
+		prNumber        = strconv.Itoa(p.PullRequestNumber)
+		forkBuildBranch = forkBuildBranchPrefix + prNumber
+	)
+
+	_, _, err := r.client.GetV3Client().Git.GetRef(ctx, r.client.Organization(), p.RepositoryName, "heads/"+forkBuildBranch)
+	if err != nil {
+		var errResp *github.ErrorResponse
+		if errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusNotFound {
+			return handlerrors.Skip("skip fork build cleanup, no fork build branch exists for pull request #%s", prNumber)
+		}
+		return fmt.Errorf("error finding fork build branch: %w", err)
+	}
+
+	return closeForkBuild(ctx, log, r.client, r.deleteBranch, p.RepositoryName, p.RepositoryURL, p.PullRequestNumber)
+}
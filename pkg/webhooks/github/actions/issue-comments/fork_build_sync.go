@@ -0,0 +1,161 @@
+package issue_comments
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v79/github"
+	"github.com/metal-stack/metal-robot/pkg/clients"
+	"github.com/metal-stack/metal-robot/pkg/config"
+	"github.com/metal-stack/metal-robot/pkg/git"
+	"github.com/metal-stack/metal-robot/pkg/webhooks/github/actions/common"
+	"github.com/metal-stack/metal-robot/pkg/webhooks/handlers"
+	handlerrors "github.com/metal-stack/metal-robot/pkg/webhooks/handlers/errors"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	workflowsPath = ".github/workflows/"
+)
+
+type forkBuildSync struct {
+	client       *clients.Github
+	enabled      bool
+	pushCommit   pushCommitFn
+	deleteBranch deleteBranchFn
+}
+
+type ForkBuildSyncParams struct {
+	RepositoryName    string
+	RepositoryURL     string
+	PullRequestNumber int
+	Fork              bool
+	Author            string
+	Sender            string
+	HeadRef           string
+	HeadCloneURL      string
+	After             string
+}
+
+func NewForkBuildSync(client *clients.Github, rawConfig map[string]any) (handlers.WebhookHandler[*ForkBuildSyncParams], error) {
+	var typedConfig config.IssueCommentsHandlerConfig
+	err := mapstructure.Decode(rawConfig, &typedConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &forkBuildSync{
+		client:       client,
+		enabled:      typedConfig.SyncForkBuilds,
+		pushCommit:   git.PushCommitToRemote,
+		deleteBranch: git.DeleteBranch,
+	}, nil
+}
+
+// Handle pushes new commits of an approved fork pull request to the fork build branch. The approval is revoked if the
+// author of the pull request changed or a non-maintainer pushed changes to the workflow files, as workflows of the fork
+// build branch run with the secrets of the organization.
+func (r *forkBuildSync) Handle(ctx context.Context, log *slog.Logger, p *ForkBuildSyncParams) error {
+	if !r.enabled {
+		return handlerrors.Skip("skip fork build sync, syncing fork builds is not enabled")
+	}
+
+	if !p.Fork {
+		return handlerrors.Skip("skip fork build sync, pull request is not from a fork")
+	}
+
+	forkPr, err := findForkBuildPullRequest(ctx, r.client, p.RepositoryName, p.PullRequestNumber)
+	if err != nil {
+		return err
+	}
+
+	if forkPr == nil {
+		return handlerrors.Skip("skip fork build sync, fork build of pull request #%d was not approved", p.PullRequestNumber)
+	}
+
+	author := approvedAuthor(forkPr.GetBody())
+	if author == "" {
+		return handlerrors.Skip("skip fork build sync, fork build of pull request #%d was approved without approval information", p.PullRequestNumber)
+	}
+
+	if author != p.Author {
+		return r.revoke(ctx, log, p, fmt.Sprintf("the author of the pull request changed from @%s to @%s", author, p.Author))
+	}
+
+	revoke, err := r.pushedWorkflows(ctx, p)
+	if err != nil {
+		return err
+	}
+
+	if revoke {
+		return r.revoke(ctx, log, p, fmt.Sprintf("@%s pushed changes to workflow files", p.Sender))
+	}
+
+	targetRepoURL, err := authenticatedRepoURL(ctx, r.client, p.RepositoryURL)
+	if err != nil {
+		return err
+	}
+
+	forkBuildBranch := forkBuildBranchPrefix + strconv.Itoa(p.PullRequestNumber)
+
+	// only the verified commit is pushed, the fork branch may already point to commits that were pushed in the meantime
+	err = r.pushCommit(p.HeadCloneURL, p.HeadRef, p.After, targetRepoURL, forkBuildBranch)
+	if err != nil {
+		return fmt.Errorf("error pushing to target remote repository: %w", err)
+	}
+
+	log.Info("synchronized fork build branch", "branch", forkBuildBranch, "commit", p.After)
+
+	return nil
+}
+
+// pushedWorkflows returns true if a non-maintainer pushed changes to workflow files compared to the last synchronized
+// state of the fork build branch. Fast-forward pushes are checked as well, as they can add workflows just like force pushes.
+func (r *forkBuildSync) pushedWorkflows(ctx context.Context, p *ForkBuildSyncParams) (bool, error) {
+	maintainer, _, err := isMaintainer(ctx, r.client, p.RepositoryName, p.Sender)
+	if err != nil {
+		return false, err
+	}
+
+	if maintainer {
+		return false, nil
+	}
+
+	forkBuildBranch := forkBuildBranchPrefix + strconv.Itoa(p.PullRequestNumber)
+
+	comparison, _, err := r.client.GetV3Client().Repositories.CompareCommits(ctx, r.client.Organization(), p.RepositoryName, forkBuildBranch, p.After, nil)
+	if err != nil {
+		return false, fmt.Errorf("error comparing fork build branch: %w", err)
+	}
+
+	for _, f := range comparison.Files {
+		if strings.HasPrefix(f.GetFilename(), workflowsPath) || strings.HasPrefix(f.GetPreviousFilename(), workflowsPath) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *forkBuildSync) revoke(ctx context.Context, log *slog.Logger, p *ForkBuildSyncParams, reason string) error {
+	log.Info("revoking fork build approval", "pull-request", p.PullRequestNumber, "reason", reason)
+
+	err := closeForkBuild(ctx, log, r.client, r.deleteBranch, p.RepositoryName, p.RepositoryURL, p.PullRequestNumber)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("The approval for the fork build was revoked because %s. A maintainer needs to review the changes and comment `%s` again.", reason, common.CommentCommandBuildFork)
+
+	_, _, err = r.client.GetV3Client().Issues.CreateComment(ctx, r.client.Organization(), p.RepositoryName, p.PullRequestNumber, &github.IssueComment{
+		Body: new(body),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to comment on pull request: %w", err)
+	}
+
+	return nil
+}
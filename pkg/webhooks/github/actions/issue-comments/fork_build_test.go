@@ -0,0 +1,423 @@
+package issue_comments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v79/github"
+	"github.com/metal-stack/metal-robot/pkg/clients"
+	"github.com/metal-stack/metal-robot/pkg/config"
+	handlerrors "github.com/metal-stack/metal-robot/pkg/webhooks/handlers/errors"
+)
+
+const (
+	testAfter = "2222222222222222222222222222222222222222"
+)
+
+// fakeGithub serves the parts of the github api that are used by the fork build handlers
+type fakeGithub struct {
+	// forkBuildBody is the body of the open fork build pull request, no fork build pull request exists if nil
+	forkBuildBody *string
+	// forkBuildFiles are the files changed between the fork build branch and the pushed commit
+	forkBuildFiles []*github.CommitFile
+	permission     string
+	branchExists   bool
+
+	comments []string
+	closed   bool
+}
+
+func (f *fakeGithub) server(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /users/metal-stack", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"login":"metal-stack"}`))
+	})
+	mux.HandleFunc("GET /repos/metal-stack/metal-robot/pulls", func(w http.ResponseWriter, r *http.Request) {
+		if head := r.URL.Query().Get("head"); head != "metal-stack:fork-build/1" {
+			t.Errorf("unexpected head filter %q", head)
+		}
+		prs := []*github.PullRequest{}
+		if f.forkBuildBody != nil {
+			prs = append(prs, &github.PullRequest{Number: new(2), Title: new("Fork build for #1"), Body: f.forkBuildBody})
+		}
+		_ = json.NewEncoder(w).Encode(prs)
+	})
+	mux.HandleFunc("PATCH /repos/metal-stack/metal-robot/pulls/2", func(w http.ResponseWriter, r *http.Request) {
+		f.closed = true
+		_, _ = w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("GET /repos/metal-stack/metal-robot/compare/{spec...}", func(w http.ResponseWriter, r *http.Request) {
+		switch spec := r.PathValue("spec"); spec {
+		case "fork-build/1..." + testAfter:
+			_ = json.NewEncoder(w).Encode(&github.CommitsComparison{Status: new("diverged"), Files: f.forkBuildFiles})
+		default:
+			t.Errorf("unexpected comparison %q", spec)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	mux.HandleFunc("GET /repos/metal-stack/metal-robot/collaborators/{user}/permission", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(&github.RepositoryPermissionLevel{Permission: &f.permission})
+	})
+	mux.HandleFunc("POST /repos/metal-stack/metal-robot/issues/1/comments", func(w http.ResponseWriter, r *http.Request) {
+		var comment github.IssueComment
+		_ = json.NewDecoder(r.Body).Decode(&comment)
+		f.comments = append(f.comments, comment.GetBody())
+		_, _ = w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("GET /repos/metal-stack/metal-robot/git/ref/heads/fork-build/1", func(w http.ResponseWriter, r *http.Request) {
+		if !f.branchExists {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"ref":"refs/heads/fork-build/1"}`))
+	})
+
+	return httptest.NewServer(mux)
+}
+
+// newTestClient returns a token authenticated client that sends all api requests to the given fake github server
+func newTestClient(t *testing.T, serverURL string) *clients.Github {
+	target, err := url.Parse(serverURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	err = os.WriteFile(tokenPath, []byte("test-token"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := clients.NewGithubWithTransport(slog.New(slog.DiscardHandler), "metal-stack", &config.GithubClient{TokenPath: tokenPath}, &redirectTransport{target: target})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client
+}
+
+// redirectTransport sends all requests to the given target instead of the github api
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func Test_approvedAuthor(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "approved author from fork build body",
+			body: forkBuildBody("1", "maintainer", "contributor"),
+			want: "contributor",
+		},
+		{
+			name: "approved author within other text",
+			body: "Fork build for #1\n\nsome notes\n<!-- fork-build-approved-author: contributor -->\nmore notes",
+			want: "contributor",
+		},
+		{
+			name: "fork build without approval information",
+			body: "Fork build for #1 triggered by @maintainer",
+			want: "",
+		},
+		{
+			name: "empty author",
+			body: "<!-- fork-build-approved-author:  -->",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := approvedAuthor(tt.body); got != tt.want {
+				t.Errorf("approvedAuthor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForkBuildSync_Handle(t *testing.T) {
+	approved := forkBuildBody("1", "maintainer", "contributor")
+
+	tests := []struct {
+		name         string
+		gh           fakeGithub
+		enabled      bool
+		fork         bool
+		author       string
+		sender       string
+		wantSkip     bool
+		wantPushed   []string
+		wantRevoked  string
+		wantDeleted  []string
+		wantComments int
+	}{
+		{
+			name:     "sync not enabled",
+			enabled:  false,
+			fork:     true,
+			wantSkip: true,
+		},
+		{
+			name:     "pull request not from a fork",
+			enabled:  true,
+			fork:     false,
+			wantSkip: true,
+		},
+		{
+			name:     "fork build not approved",
+			gh:       fakeGithub{},
+			enabled:  true,
+			fork:     true,
+			author:   "contributor",
+			wantSkip: true,
+		},
+		{
+			name:     "fork build approved without approval information",
+			gh:       fakeGithub{forkBuildBody: new("Fork build for #1 triggered by @maintainer")},
+			enabled:  true,
+			fork:     true,
+			author:   "contributor",
+			wantSkip: true,
+		},
+		{
+			name:        "author changed",
+			gh:          fakeGithub{forkBuildBody: &approved},
+			enabled:     true,
+			fork:        true,
+			author:      "someone-else",
+			sender:      "someone-else",
+			wantRevoked: "the author of the pull request changed from @contributor to @someone-else",
+			wantDeleted: []string{"fork-build/1"},
+		},
+		{
+			name: "push of a maintainer is synchronized",
+			gh: fakeGithub{
+				forkBuildBody:  &approved,
+				permission:     "write",
+				forkBuildFiles: []*github.CommitFile{{Filename: new(".github/workflows/docker.yaml")}},
+			},
+			enabled:    true,
+			fork:       true,
+			author:     "contributor",
+			sender:     "maintainer",
+			wantPushed: []string{testAfter + "->fork-build/1"},
+		},
+		{
+			name: "push without workflow changes is synchronized",
+			gh: fakeGithub{
+				forkBuildBody:  &approved,
+				permission:     "read",
+				forkBuildFiles: []*github.CommitFile{{Filename: new("main.go")}},
+			},
+			enabled:    true,
+			fork:       true,
+			author:     "contributor",
+			sender:     "contributor",
+			wantPushed: []string{testAfter + "->fork-build/1"},
+		},
+		{
+			name: "push without changes is synchronized",
+			gh: fakeGithub{
+				forkBuildBody: &approved,
+				permission:    "read",
+			},
+			enabled:    true,
+			fork:       true,
+			author:     "contributor",
+			sender:     "contributor",
+			wantPushed: []string{testAfter + "->fork-build/1"},
+		},
+		{
+			name: "push with workflow changes revokes approval",
+			gh: fakeGithub{
+				forkBuildBody:  &approved,
+				permission:     "read",
+				forkBuildFiles: []*github.CommitFile{{Filename: new("main.go")}, {Filename: new(".github/workflows/docker.yaml")}},
+			},
+			enabled:     true,
+			fork:        true,
+			author:      "contributor",
+			sender:      "contributor",
+			wantRevoked: "@contributor pushed changes to workflow files",
+			wantDeleted: []string{"fork-build/1"},
+		},
+		{
+			name: "push renaming a workflow revokes approval",
+			gh: fakeGithub{
+				forkBuildBody:  &approved,
+				permission:     "read",
+				forkBuildFiles: []*github.CommitFile{{Filename: new("docker.yaml"), PreviousFilename: new(".github/workflows/docker.yaml")}},
+			},
+			enabled:     true,
+			fork:        true,
+			author:      "contributor",
+			sender:      "contributor",
+			wantRevoked: "@contributor pushed changes to workflow files",
+			wantDeleted: []string{"fork-build/1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := tt.gh.server(t)
+			defer server.Close()
+
+			client := newTestClient(t, server.URL)
+
+			var pushed, deleted []string
+
+			r := &forkBuildSync{
+				client:  client,
+				enabled: tt.enabled,
+				pushCommit: func(remoteURL, remoteBranch, commit, targetURL, targetBranch string) error {
+					if remoteURL != "https://github.com/contributor/metal-robot.git" || remoteBranch != "feature" {
+						t.Errorf("unexpected push source %s %s", remoteURL, remoteBranch)
+					}
+					pushed = append(pushed, commit+"->"+targetBranch)
+					return nil
+				},
+				deleteBranch: func(repoURL, branch string) error {
+					deleted = append(deleted, branch)
+					return nil
+				},
+			}
+
+			err := r.Handle(context.Background(), slog.New(slog.DiscardHandler), &ForkBuildSyncParams{
+				RepositoryName:    "metal-robot",
+				RepositoryURL:     "https://github.com/metal-stack/metal-robot",
+				PullRequestNumber: 1,
+				Fork:              tt.fork,
+				Author:            tt.author,
+				Sender:            tt.sender,
+				HeadRef:           "feature",
+				HeadCloneURL:      "https://github.com/contributor/metal-robot.git",
+				After:             testAfter,
+			})
+
+			var skipErr handlerrors.SkipErr
+			if skipped := errors.As(err, &skipErr); skipped != tt.wantSkip {
+				t.Errorf("Handle() error = %v, wantSkip %v", err, tt.wantSkip)
+			} else if !skipped && err != nil {
+				t.Errorf("Handle() error = %v", err)
+			}
+
+			if diff := cmp.Diff(tt.wantPushed, pushed); diff != "" {
+				t.Errorf("Handle() pushed diff: %v", diff)
+			}
+			if diff := cmp.Diff(tt.wantDeleted, deleted); diff != "" {
+				t.Errorf("Handle() deleted diff: %v", diff)
+			}
+
+			if tt.wantRevoked == "" {
+				if len(tt.gh.comments) > 0 || tt.gh.closed {
+					t.Errorf("Handle() unexpectedly revoked the approval: %v", tt.gh.comments)
+				}
+				return
+			}
+
+			if !tt.gh.closed {
+				t.Errorf("Handle() expected the fork build pull request to be closed")
+			}
+			if len(tt.gh.comments) != 1 || !strings.Contains(tt.gh.comments[0], tt.wantRevoked) {
+				t.Errorf("Handle() expected revocation comment containing %q, got %v", tt.wantRevoked, tt.gh.comments)
+			}
+		})
+	}
+}
+
+func TestForkBuildCleanup_Handle(t *testing.T) {
+	approved := forkBuildBody("1", "maintainer", "contributor")
+
+	tests := []struct {
+		name        string
+		gh          fakeGithub
+		fork        bool
+		wantSkip    bool
+		wantClosed  bool
+		wantDeleted []string
+	}{
+		{
+			name:     "pull request not from a fork",
+			fork:     false,
+			wantSkip: true,
+		},
+		{
+			name:     "no fork build branch",
+			gh:       fakeGithub{},
+			fork:     true,
+			wantSkip: true,
+		},
+		{
+			name:        "fork build branch without pull request",
+			gh:          fakeGithub{branchExists: true},
+			fork:        true,
+			wantDeleted: []string{"fork-build/1"},
+		},
+		{
+			name:        "fork build is closed",
+			gh:          fakeGithub{branchExists: true, forkBuildBody: &approved},
+			fork:        true,
+			wantClosed:  true,
+			wantDeleted: []string{"fork-build/1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := tt.gh.server(t)
+			defer server.Close()
+
+			client := newTestClient(t, server.URL)
+
+			var deleted []string
+
+			r := &forkBuildCleanup{
+				client: client,
+				deleteBranch: func(repoURL, branch string) error {
+					deleted = append(deleted, branch)
+					return nil
+				},
+			}
+
+			err := r.Handle(context.Background(), slog.New(slog.DiscardHandler), &ForkBuildCleanupParams{
+				RepositoryName:    "metal-robot",
+				RepositoryURL:     "https://github.com/metal-stack/metal-robot",
+				PullRequestNumber: 1,
+				Fork:              tt.fork,
+			})
+
+			var skipErr handlerrors.SkipErr
+			if skipped := errors.As(err, &skipErr); skipped != tt.wantSkip {
+				t.Errorf("Handle() error = %v, wantSkip %v", err, tt.wantSkip)
+			} else if !skipped && err != nil {
+				t.Errorf("Handle() error = %v", err)
+			}
+
+			if tt.gh.closed != tt.wantClosed {
+				t.Errorf("Handle() closed = %v, want %v", tt.gh.closed, tt.wantClosed)
+			}
+			if diff := cmp.Diff(tt.wantDeleted, deleted); diff != "" {
+				t.Errorf("Handle() deleted diff: %v", diff)
+			}
+		})
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"net/url"
 	"strconv"
 	"strings"
 
@@ -46,15 +45,12 @@ func New(client *clients.Github, rawConfig map[string]any) (handlers.WebhookHand
 
 // Handle applies actions on issues comments, e.g. executes ad hoc commands
 func (r *IssueCommentsAction) Handle(ctx context.Context, log *slog.Logger, p *Params) error {
-	level, _, err := r.client.GetV3Client().Repositories.GetPermissionLevel(ctx, r.client.Organization(), p.RepositoryName, p.User)
+	maintainer, perm, err := isMaintainer(ctx, r.client, p.RepositoryName, p.User)
 	if err != nil {
-		return fmt.Errorf("error determining collaborator status: %w", err)
+		return err
 	}
 
-	switch perm := *level.Permission; perm {
-	case "admin", "write":
-		// in this case we can continue
-	default:
+	if !maintainer {
 		return handlerrors.Skip("skip handling issues comment action, author %q does not have admin permissions on this repo (but only %q)", p.User, perm)
 	}
 
@@ -116,35 +112,31 @@ func (r *IssueCommentsAction) buildForkPR(ctx context.Context, log *slog.Logger,
 		return handlerrors.Skip("skip handling issues comment action, pull request is not from a fork")
 	}
 
-	token, err := r.client.GitToken(ctx)
+	targetRepoURL, err := authenticatedRepoURL(ctx, r.client, p.RepositoryURL)
 	if err != nil {
-		return fmt.Errorf("error creating git token: %w", err)
+		return err
 	}
 
-	targetRepoURL, err := url.Parse(p.RepositoryURL)
-	if err != nil {
-		return fmt.Errorf("unable to parse repository url: %w", err)
-	}
-	targetRepoURL.User = url.UserPassword("x-access-token", token)
-
 	var (
 		commitMessage   = "Triggering fork build approved by maintainer"
 		headRef         = *pullRequest.Head.Ref
 		prNumber        = strconv.Itoa(*pullRequest.Number)
-		forkBuildBranch = "fork-build/" + prNumber
-		forkPrTitle     = "Fork build for #" + prNumber
+		forkBuildBranch = forkBuildBranchPrefix + prNumber
+		forkPrTitle     = forkBuildTitlePrefix + prNumber
 	)
 
-	err = git.PushToRemote(*pullRequest.Head.Repo.CloneURL, headRef, targetRepoURL.String(), forkBuildBranch, commitMessage)
+	err = git.PushToRemote(*pullRequest.Head.Repo.CloneURL, headRef, targetRepoURL, forkBuildBranch, commitMessage)
 	if err != nil {
 		return fmt.Errorf("error pushing to target remote repository: %w", err)
 	}
 
+	forkPrBody := forkBuildBody(prNumber, p.User, pullRequest.GetUser().GetLogin())
+
 	forkPr, _, err := r.client.GetV3Client().PullRequests.Create(ctx, r.client.Organization(), p.RepositoryName, &github.NewPullRequest{
 		Title:               new(forkPrTitle),
 		Head:                new(forkBuildBranch),
 		Base:                pullRequest.Base.Ref,
-		Body:                new("Fork build for #" + prNumber + " triggered by @" + p.User),
+		Body:                new(forkPrBody),
 		MaintainerCanModify: new(true),
 		Draft:               new(true),
 	})
@@ -152,6 +144,21 @@ func (r *IssueCommentsAction) buildForkPR(ctx context.Context, log *slog.Logger,
 		if !strings.Contains(err.Error(), "A pull request already exists") {
 			return fmt.Errorf("unable to create pull request: %w", err)
 		}
+
+		// renew the approval for the current author of the fork pull request
+		forkPr, err = findForkBuildPullRequest(ctx, r.client, p.RepositoryName, *pullRequest.Number)
+		if err != nil {
+			return err
+		}
+
+		if forkPr != nil {
+			_, _, err = r.client.GetV3Client().PullRequests.Edit(ctx, r.client.Organization(), p.RepositoryName, forkPr.GetNumber(), &github.PullRequest{
+				Body: new(forkPrBody),
+			})
+			if err != nil {
+				return fmt.Errorf("unable to update fork build pull request: %w", err)
+			}
+		}
 	}
 
 	log.Info("triggered fork build action by pushing to fork-build branch", "branch", forkBuildBranch, "pull-request-url", forkPr.GetURL())
@@ -176,19 +183,13 @@ func (r *IssueCommentsAction) tag(ctx context.Context, log *slog.Logger, p *Para
 		return fmt.Errorf("error finding issue related pull request: %w", err)
 	}
 
-	token, err := r.client.GitToken(ctx)
-	if err != nil {
-		return fmt.Errorf("error creating git token: %w", err)
-	}
-
-	targetRepoURL, err := url.Parse(p.RepositoryURL)
+	targetRepoURL, err := authenticatedRepoURL(ctx, r.client, p.RepositoryURL)
 	if err != nil {
-		return fmt.Errorf("unable to parse repository url: %w", err)
+		return err
 	}
-	targetRepoURL.User = url.UserPassword("x-access-token", token)
 
 	headRef := *pullRequest.Head.Ref
-	err = git.CreateTag(targetRepoURL.String(), headRef, tag, p.User)
+	err = git.CreateTag(targetRepoURL, headRef, tag, p.User, r.client.GitIdentity())
 	if err != nil {
 		return fmt.Errorf("unable to create git tag: %w", err)
 	}
@@ -86,6 +86,9 @@ func New(client *clients.Github, rawConfig map[string]any) (handlers.WebhookHand
 	if typedConfig.ReleaseTitleTemplate != nil {
 		releaseTitleTemplate = *typedConfig.ReleaseTitleTemplate
 	}
+	if err := utils.ValidateTemplate(releaseTitleTemplate); err != nil {
+		return nil, fmt.Errorf("invalid release title template: %w", err)
+	}
 	if typedConfig.DraftHeadline != nil {
 		draftHeadline = *typedConfig.DraftHeadline
 	}
@@ -438,14 +441,22 @@ func (r *releaseDrafter) createOrUpdateRelease(ctx context.Context, log *slog.Lo
 
 		log.Info("release draft updated", "version", p.TagName)
 	} else {
+		data := utils.NewTemplateData(infos.releaseTag)
+		data.RepositoryName = r.repoName
+
+		title, err := utils.RenderTemplate(r.titleTemplate, data, infos.releaseTag)
+		if err != nil {
+			return fmt.Errorf("error rendering release title: %w", err)
+		}
+
 		newDraft := &github.RepositoryRelease{
 			TagName: new(infos.releaseTag),
-			Name:    new(fmt.Sprintf(r.titleTemplate, infos.releaseTag)),
+			Name:    new(title),
 			Body:    &body,
 			Draft:   new(true),
 		}
 
-		_, _, err := r.client.GetV3Client().Repositories.CreateRelease(ctx, r.client.Organization(), r.repoName, newDraft)
+		_, _, err = r.client.GetV3Client().Repositories.CreateRelease(ctx, r.client.Organization(), r.repoName, newDraft)
 		if err != nil {
 			return fmt.Errorf("unable to create release draft: %w", err)
 		}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"net/url"
 	"strings"
 	"sync"
 
@@ -16,6 +15,7 @@ import (
 	"github.com/metal-stack/metal-robot/pkg/clients"
 	"github.com/metal-stack/metal-robot/pkg/config"
 	"github.com/metal-stack/metal-robot/pkg/git"
+	"github.com/metal-stack/metal-robot/pkg/utils"
 	"github.com/metal-stack/metal-robot/pkg/webhooks/github/actions/common"
 	"github.com/metal-stack/metal-robot/pkg/webhooks/handlers"
 	handlerrors "github.com/metal-stack/metal-robot/pkg/webhooks/handlers/errors"
@@ -32,6 +32,7 @@ type yamlTranslateReleases struct {
 	repoURL               string
 	repoName              string
 	pullRequestTitle      string
+	commitBackend         config.CommitBackend
 
 	lock *multilock.Lock
 }
@@ -53,7 +54,7 @@ type Params struct {
 	Sender         string
 }
 
-func New(client *clients.Github, rawConfig map[string]any) (handlers.WebhookHandler[*Params], error) {
+func New(client *clients.Github, rawConfig map[string]any, patcherOpts filepatchers.Options) (handlers.WebhookHandler[*Params], error) {
 	var (
 		branch                = "develop"
 		branchBase            = "master"
@@ -82,10 +83,18 @@ func New(client *clients.Github, rawConfig map[string]any) (handlers.WebhookHand
 	if typedConfig.CommitMsgTemplate != nil {
 		commitMessageTemplate = *typedConfig.CommitMsgTemplate
 	}
+	if err := utils.ValidateTemplate(commitMessageTemplate); err != nil {
+		return nil, fmt.Errorf("invalid commit message template: %w", err)
+	}
 	if typedConfig.PullRequestTitle != nil {
 		pullRequestTitle = *typedConfig.PullRequestTitle
 	}
 
+	commitBackend, err := common.ParseCommitBackend(typedConfig.CommitBackend)
+	if err != nil {
+		return nil, err
+	}
+
 	translationMap := make(map[string][]yamlTranslation)
 	for n, translations := range typedConfig.SourceRepos {
 		for _, t := range translations {
@@ -100,7 +109,7 @@ func New(client *clients.Github, rawConfig map[string]any) (handlers.WebhookHand
 			}
 
 			for _, m := range t.To {
-				to, err := filepatchers.InitPatcher(m)
+				to, err := filepatchers.InitPatcher(m, patcherOpts)
 				if err != nil {
 					return nil, err
 				}
@@ -125,6 +134,7 @@ func New(client *clients.Github, rawConfig map[string]any) (handlers.WebhookHand
 		repoURL:               typedConfig.TargetRepositoryURL,
 		repoName:              typedConfig.TargetRepositoryName,
 		pullRequestTitle:      pullRequestTitle,
+		commitBackend:         commitBackend,
 		lock:                  multilock.New(typedConfig.TargetRepositoryName),
 	}, nil
 }
@@ -184,62 +194,48 @@ func (r *yamlTranslateReleases) Handle(ctx context.Context, log *slog.Logger, p
 	r.lock.Lock()
 	defer once.Do(func() { r.lock.Unlock() })
 
-	token, err := r.client.GitToken(ctx)
-	if err != nil {
-		return fmt.Errorf("error creating git token: %w", err)
-	}
-
-	sourceRepoURL, err := url.Parse(p.RepositoryURL)
-	if err != nil {
-		return err
-	}
-	sourceRepoURL.User = url.UserPassword("x-access-token", token)
-
-	sourceRepository, err := git.ShallowClone(sourceRepoURL.String(), r.branch, 1)
-	if err != nil {
-		return err
-	}
-
-	targetRepoURL, err := url.Parse(r.repoURL)
-	if err != nil {
-		return err
-	}
-	targetRepoURL.User = url.UserPassword("x-access-token", token)
-
-	targetRepository, err := git.ShallowClone(targetRepoURL.String(), r.branch, 1)
+	sourceBranch, err := common.OpenBranch(ctx, r.client, r.commitBackend, p.RepositoryName, p.RepositoryURL, r.branch)
 	if err != nil {
 		return err
 	}
 
-	reader := func(file string) ([]byte, error) {
-		return git.ReadRepoFile(targetRepository, file)
+	open := func() (git.Branch, error) {
+		return common.OpenBranch(ctx, r.client, r.commitBackend, r.repoName, r.repoURL, r.branch)
 	}
 
-	writer := func(file string, content []byte) error {
-		return git.WriteRepoFile(targetRepository, file, content)
-	}
+	data := common.ReleaseTemplateData(p.RepositoryName, p.RepositoryURL, p.Sender, tag)
 
-	for _, translation := range translations {
-		content, err := git.ReadRepoFile(sourceRepository, translation.from.file)
-		if err != nil {
-			return fmt.Errorf("error reading content from source repository file: %w", err)
-		}
+	// assets are only downloaded once, also when the changes are applied again after a concurrent modification
+	ctx = filepatchers.WithChecksumCache(ctx)
 
-		value, err := filepatchers.GetYAML(content, translation.from.yamlPath)
-		if err != nil {
-			return fmt.Errorf("error reading value from source repository file: %w", err)
-		}
+	apply := func(targetBranch git.Branch) error {
+		for _, translation := range translations {
+			content, err := sourceBranch.ReadFile(ctx, translation.from.file)
+			if err != nil {
+				return fmt.Errorf("error reading content from source repository file: %w", err)
+			}
 
-		for _, patch := range translation.to {
-			err = patch.Apply(reader, writer, value)
+			value, err := filepatchers.GetYAML(content, translation.from.yamlPath)
 			if err != nil {
-				return fmt.Errorf("error applying translate updates: %w", err)
+				return fmt.Errorf("error reading value from source repository file: %w", err)
+			}
+
+			for _, patch := range translation.to {
+				err = filepatchers.Apply(ctx, log, patch, targetBranch, value, data)
+				if err != nil {
+					return fmt.Errorf("error applying translate updates: %w", err)
+				}
 			}
 		}
+		return nil
+	}
+
+	commitMessage, err := utils.RenderTemplate(r.commitMessageTemplate, data, p.RepositoryName, tag)
+	if err != nil {
+		return fmt.Errorf("error rendering commit message: %w", err)
 	}
 
-	commitMessage := fmt.Sprintf(r.commitMessageTemplate, p.RepositoryName, tag)
-	hash, err := git.CommitAndPush(targetRepository, commitMessage)
+	hash, err := common.CommitWithRetry(ctx, log, open, apply, commitMessage)
 	if err != nil {
 		if errors.Is(err, git.ErrNoChanges) {
 			log.Debug("skip push to target repository because nothing changed")
@@ -10,6 +10,7 @@ import (
 	"github.com/metal-stack/metal-robot/pkg/clients"
 	"github.com/metal-stack/metal-robot/pkg/config"
 	"github.com/metal-stack/metal-robot/pkg/webhooks/handlers"
+	filepatchers "github.com/metal-stack/metal-robot/pkg/webhooks/modifiers/file-patchers"
 )
 
 type Webhook struct {
@@ -18,8 +19,8 @@ type Webhook struct {
 }
 
 // NewGithubWebhook returns a new webhook controller
-func NewGithubWebhook(logger *slog.Logger, cfg config.Webhook, clients clients.ClientMap) (*Webhook, error) {
-	err := initHandlers(logger, clients, cfg.ServePath, cfg.Actions)
+func NewGithubWebhook(logger *slog.Logger, cfg config.Webhook, clients clients.ClientMap, patcherOpts filepatchers.Options) (*Webhook, error) {
+	err := initHandlers(logger, clients, cfg.ServePath, cfg.Actions, patcherOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -21,21 +21,23 @@ import (
 	yaml_translate_releases "github.com/metal-stack/metal-robot/pkg/webhooks/github/actions/yaml-translate-releases"
 	"github.com/metal-stack/metal-robot/pkg/webhooks/handlers"
 	handlerrors "github.com/metal-stack/metal-robot/pkg/webhooks/handlers/errors"
+	filepatchers "github.com/metal-stack/metal-robot/pkg/webhooks/modifiers/file-patchers"
 
 	"github.com/google/go-github/v79/github"
 	"github.com/metal-stack/metal-lib/pkg/pointer"
 )
 
 const (
-	githubActionClosed   string = "closed"
-	githubActionCreated  string = "created"
-	githubActionEdited   string = "edited"
-	githubActionOpened   string = "opened"
-	githubActionReleased string = "released"
-	githubActionTyped    string = "typed"
+	githubActionClosed      string = "closed"
+	githubActionCreated     string = "created"
+	githubActionEdited      string = "edited"
+	githubActionOpened      string = "opened"
+	githubActionReleased    string = "released"
+	githubActionSynchronize string = "synchronize"
+	githubActionTyped       string = "typed"
 )
 
-func initHandlers(logger *slog.Logger, cs clients.ClientMap, path string, cfg config.WebhookActions) error {
+func initHandlers(logger *slog.Logger, cs clients.ClientMap, path string, cfg config.WebhookActions, patcherOpts filepatchers.Options) error {
 	for _, spec := range cfg {
 		c, ok := cs[spec.Client]
 		if !ok {
@@ -128,7 +130,7 @@ func initHandlers(logger *slog.Logger, cs clients.ClientMap, path string, cfg co
 			})
 
 		case config.ActionAggregateReleases:
-			h, err := aggregate_releases.New(client, spec.Args)
+			h, err := aggregate_releases.New(client, spec.Args, patcherOpts)
 			if err != nil {
 				return err
 			}
@@ -191,7 +193,7 @@ func initHandlers(logger *slog.Logger, cs clients.ClientMap, path string, cfg co
 			})
 
 		case config.ActionDistributeReleases:
-			h, err := distribute_releases.New(client, spec.Args)
+			h, err := distribute_releases.New(client, spec.Args, patcherOpts)
 			if err != nil {
 				return err
 			}
@@ -201,9 +203,13 @@ func initHandlers(logger *slog.Logger, cs clients.ClientMap, path string, cfg co
 					created = pointer.SafeDeref(event.Created)
 					ref     = pointer.SafeDeref(event.Ref)
 
-					repo = pointer.SafeDeref(event.Repo)
+					repo   = pointer.SafeDeref(event.Repo)
+					sender = pointer.SafeDeref(event.Sender)
 
 					repoName = pointer.SafeDeref(repo.Name)
+					repoURL  = pointer.SafeDeref(repo.HTMLURL)
+
+					login = pointer.SafeDeref(sender.Login)
 
 					tagName = extractTag(event)
 				)
@@ -218,7 +224,9 @@ func initHandlers(logger *slog.Logger, cs clients.ClientMap, path string, cfg co
 
 				return &distribute_releases.Params{
 					RepositoryName: repoName,
+					RepositoryURL:  repoURL,
 					TagName:        tagName,
+					Sender:         login,
 				}, nil
 			})
 
@@ -297,7 +305,7 @@ func initHandlers(logger *slog.Logger, cs clients.ClientMap, path string, cfg co
 			})
 
 		case config.ActionYAMLTranslateReleases:
-			h, err := yaml_translate_releases.New(client, spec.Args)
+			h, err := yaml_translate_releases.New(client, spec.Args, patcherOpts)
 			if err != nil {
 				return err
 			}
@@ -481,6 +489,70 @@ func initHandlers(logger *slog.Logger, cs clients.ClientMap, path string, cfg co
 					PullRequestNumber: pullRequestNumber,
 				}, nil
 			})
+
+			h2, err := issue_comments.NewForkBuildCleanup(client, spec.Args)
+			if err != nil {
+				return err
+			}
+
+			handlers.Register(string(t), path, h2, func(event *github.PullRequestEvent) (*issue_comments.ForkBuildCleanupParams, error) {
+				var (
+					action      = pointer.SafeDeref(event.Action)
+					repo        = pointer.SafeDeref(event.Repo)
+					pullRequest = pointer.SafeDeref(event.PullRequest)
+					head        = pointer.SafeDeref(pullRequest.Head)
+					headRepo    = pointer.SafeDeref(head.Repo)
+
+					repoName     = pointer.SafeDeref(repo.Name)
+					repoCloneURL = pointer.SafeDeref(repo.CloneURL)
+					number       = pointer.SafeDeref(pullRequest.Number)
+					fork         = pointer.SafeDeref(headRepo.Fork)
+				)
+
+				if action != githubActionClosed {
+					return nil, handlerrors.SkipOnlyActions(githubActionClosed)
+				}
+
+				return &issue_comments.ForkBuildCleanupParams{
+					RepositoryName:    repoName,
+					RepositoryURL:     repoCloneURL,
+					PullRequestNumber: number,
+					Fork:              fork,
+				}, nil
+			})
+
+			h3, err := issue_comments.NewForkBuildSync(client, spec.Args)
+			if err != nil {
+				return err
+			}
+
+			handlers.Register(string(t), path, h3, func(event *github.PullRequestEvent) (*issue_comments.ForkBuildSyncParams, error) {
+				var (
+					action      = pointer.SafeDeref(event.Action)
+					repo        = pointer.SafeDeref(event.Repo)
+					sender      = pointer.SafeDeref(event.Sender)
+					pullRequest = pointer.SafeDeref(event.PullRequest)
+					user        = pointer.SafeDeref(pullRequest.User)
+					head        = pointer.SafeDeref(pullRequest.Head)
+					headRepo    = pointer.SafeDeref(head.Repo)
+				)
+
+				if action != githubActionSynchronize {
+					return nil, handlerrors.SkipOnlyActions(githubActionSynchronize)
+				}
+
+				return &issue_comments.ForkBuildSyncParams{
+					RepositoryName:    pointer.SafeDeref(repo.Name),
+					RepositoryURL:     pointer.SafeDeref(repo.CloneURL),
+					PullRequestNumber: pointer.SafeDeref(pullRequest.Number),
+					Fork:              pointer.SafeDeref(headRepo.Fork),
+					Author:            pointer.SafeDeref(user.Login),
+					Sender:            pointer.SafeDeref(sender.Login),
+					HeadRef:           pointer.SafeDeref(head.Ref),
+					HeadCloneURL:      pointer.SafeDeref(headRepo.CloneURL),
+					After:             pointer.SafeDeref(event.After),
+				}, nil
+			})
 		default:
 			return fmt.Errorf("handler type not supported: %s", t)
 		}
@@ -10,6 +10,7 @@ import (
 	"github.com/metal-stack/metal-robot/pkg/clients"
 	"github.com/metal-stack/metal-robot/pkg/config"
 	"github.com/metal-stack/metal-robot/pkg/webhooks/handlers"
+	filepatchers "github.com/metal-stack/metal-robot/pkg/webhooks/modifiers/file-patchers"
 )
 
 var (
@@ -24,13 +25,13 @@ type Webhook struct {
 }
 
 // NewGitlabWebhook returns a new webhook controller
-func NewGitlabWebhook(logger *slog.Logger, cfg config.Webhook, clients clients.ClientMap) (*Webhook, error) {
+func NewGitlabWebhook(logger *slog.Logger, cfg config.Webhook, clients clients.ClientMap, patcherOpts filepatchers.Options) (*Webhook, error) {
 	hook, err := glwebhooks.New(glwebhooks.Options.Secret(cfg.Secret))
 	if err != nil {
 		return nil, err
 	}
 
-	err = initHandlers(logger, clients, cfg.ServePath, cfg.Actions)
+	err = initHandlers(logger, clients, cfg.ServePath, cfg.Actions, patcherOpts)
 	if err != nil {
 		return nil, err
 	}
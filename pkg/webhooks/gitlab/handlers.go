@@ -9,11 +9,12 @@ import (
 	"github.com/metal-stack/metal-robot/pkg/config"
 	aggregate_releases "github.com/metal-stack/metal-robot/pkg/webhooks/github/actions/aggregate-releases"
 	"github.com/metal-stack/metal-robot/pkg/webhooks/handlers"
+	filepatchers "github.com/metal-stack/metal-robot/pkg/webhooks/modifiers/file-patchers"
 
 	glwebhooks "github.com/go-playground/webhooks/v6/gitlab"
 )
 
-func initHandlers(logger *slog.Logger, cs clients.ClientMap, path string, cfg config.WebhookActions) error {
+func initHandlers(logger *slog.Logger, cs clients.ClientMap, path string, cfg config.WebhookActions, patcherOpts filepatchers.Options) error {
 	for _, spec := range cfg {
 		c, ok := cs[spec.Client]
 		if !ok {
@@ -31,7 +32,7 @@ func initHandlers(logger *slog.Logger, cs clients.ClientMap, path string, cfg co
 
 		switch t := spec.Type; t {
 		case config.ActionAggregateReleases:
-			h, err := aggregate_releases.New(client, spec.Args)
+			h, err := aggregate_releases.New(client, spec.Args, patcherOpts)
 			if err != nil {
 				return err
 			}
@@ -9,20 +9,26 @@ import (
 	"github.com/metal-stack/metal-robot/pkg/config"
 	"github.com/metal-stack/metal-robot/pkg/webhooks/github"
 	"github.com/metal-stack/metal-robot/pkg/webhooks/gitlab"
+	filepatchers "github.com/metal-stack/metal-robot/pkg/webhooks/modifiers/file-patchers"
 )
 
 func InitWebhooks(logger *slog.Logger, cs clients.ClientMap, c *config.Configuration) error {
+	var patcherOpts filepatchers.Options
+	if c.ExecPatch != nil {
+		patcherOpts.ExecAllowedCommands = c.ExecPatch.AllowedCommands
+	}
+
 	for _, w := range c.Webhooks {
 		switch w.VCS {
 		case config.Github:
-			controller, err := github.NewGithubWebhook(logger.WithGroup("github-webhook"), w, cs)
+			controller, err := github.NewGithubWebhook(logger.WithGroup("github-webhook"), w, cs, patcherOpts)
 			if err != nil {
 				return err
 			}
 			http.HandleFunc(w.ServePath, controller.Handle)
 			logger.Info("initialized github webhook", "serve-path", w.ServePath)
 		case config.Gitlab:
-			controller, err := gitlab.NewGitlabWebhook(logger.WithGroup("gitlab-webhook"), w, cs)
+			controller, err := gitlab.NewGitlabWebhook(logger.WithGroup("gitlab-webhook"), w, cs, patcherOpts)
 			if err != nil {
 				return err
 			}
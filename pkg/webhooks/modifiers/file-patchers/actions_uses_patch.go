@@ -0,0 +1,259 @@
+package filepatchers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/metal-stack/metal-robot/pkg/config"
+	"github.com/metal-stack/metal-robot/pkg/git"
+	"github.com/metal-stack/metal-robot/pkg/utils"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	workflowsDir = ".github/workflows"
+)
+
+var (
+	usesRegex = regexp.MustCompile(`^(\s*(?:-\s+)?uses:\s*)(['"]?)([^@\s'"]+)@([^\s'"#]+)(['"]?)(.*)$`)
+	shaRegex  = regexp.MustCompile(`^[0-9a-f]{40}$`)
+	// commentVersionRegex finds the version in the trailing comment of a pinned reference, e.g. # v1.2.3 pinned for CVE-2024-1234
+	commentVersionRegex = regexp.MustCompile(`(?:^|\s)(v?\d+\.\d+(?:\.\d+)?(?:-[0-9A-Za-z.]+)?)(?:\s|$)`)
+)
+
+// ActionsUsesPatch updates the ref of uses references to the released repository in github workflows and composite actions.
+type ActionsUsesPatch struct {
+	repository     string
+	updatePinned   bool
+	versionCompare bool
+	policy         *utils.VersionPolicy
+
+	// resolveTag returns the commit of a tag, it can be replaced for testing purposes
+	resolveTag func(repoURL, tag string) (string, error)
+}
+
+func newActionsUsesPatch(rawConfig map[string]any) (*ActionsUsesPatch, error) {
+	var typedConfig config.ActionsUsesPatchConfig
+	err := mapstructure.Decode(rawConfig, &typedConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	p := ActionsUsesPatch{
+		repository:     typedConfig.Repository,
+		versionCompare: true,
+		resolveTag:     git.ResolveTag,
+	}
+
+	if typedConfig.UpdatePinned != nil {
+		p.updatePinned = *typedConfig.UpdatePinned
+	}
+
+	if typedConfig.VersionCompare != nil {
+		p.versionCompare = *typedConfig.VersionCompare
+	}
+
+	// workflows of other repositories should not follow prereleases unless explicitly configured
+	p.policy, err = utils.NewVersionPolicy(typedConfig.VersionPolicy, utils.PrereleaseSkip)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (p ActionsUsesPatch) Apply(_ ContentReader, _ ContentWriter, _ string, _ utils.TemplateData) error {
+	return fmt.Errorf("patching uses references requires access to the repository")
+}
+
+// ApplyRepository rewrites the refs in all workflow files and action.yml files of the repository
+func (p ActionsUsesPatch) ApplyRepository(ctx context.Context, _ *slog.Logger, repo Repository, newValue string, _ utils.TemplateData) error {
+	files, err := repo.ListFiles(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	var (
+		sha    string
+		pinned = func() (string, error) {
+			if sha == "" {
+				sha, err = p.resolveTag("https://github.com/"+p.repository+".git", newValue)
+			}
+			return sha, err
+		}
+	)
+
+	for _, file := range files {
+		if !isWorkflowFile(file) {
+			continue
+		}
+
+		content, err := repo.ReadFile(ctx, file)
+		if err != nil {
+			return err
+		}
+
+		lines := strings.Split(string(content), "\n")
+		changed := false
+
+		for i, line := range lines {
+			updated, err := p.patchLine(line, newValue, pinned)
+			if err != nil {
+				return fmt.Errorf("error patching %s: %w", file, err)
+			}
+
+			if updated != line {
+				lines[i] = updated
+				changed = true
+			}
+		}
+
+		if !changed {
+			continue
+		}
+
+		err = repo.WriteFile(ctx, file, []byte(strings.Join(lines, "\n")))
+		if err != nil {
+			return fmt.Errorf("error writing patch file %w", err)
+		}
+	}
+
+	return nil
+}
+
+func isWorkflowFile(file string) bool {
+	ext := path.Ext(file)
+	if ext != ".yml" && ext != ".yaml" {
+		return false
+	}
+
+	if path.Dir(file) == workflowsDir {
+		return true
+	}
+
+	name := path.Base(file)
+
+	return name == "action.yml" || name == "action.yaml"
+}
+
+func (p ActionsUsesPatch) patchLine(line, newValue string, pinned func() (string, error)) (string, error) {
+	matches := usesRegex.FindStringSubmatch(line)
+	if matches == nil {
+		return line, nil
+	}
+
+	var (
+		prefix, quote, ref, suffix = matches[1], matches[2], matches[4], matches[6]
+		target                     = matches[3]
+		parts                      = strings.Split(target, "/")
+	)
+
+	if len(parts) < 2 || !strings.EqualFold(parts[0]+"/"+parts[1], p.repository) {
+		return line, nil
+	}
+
+	if shaRegex.MatchString(ref) {
+		if !p.updatePinned {
+			return line, nil
+		}
+
+		// the version of a pinned reference is kept in a trailing comment by convention
+		current, newSuffix := pinnedComment(suffix, newValue)
+		allowed, err := allowsUpdate(current, newValue, false, p.versionCompare, p.policy)
+		if err != nil || !allowed {
+			return line, err
+		}
+
+		sha, err := pinned()
+		if err != nil {
+			return "", err
+		}
+
+		return prefix + quote + target + "@" + sha + quote + newSuffix, nil
+	}
+
+	newRef, floating, ok := refWithPrecision(ref, newValue)
+	if !ok {
+		// branches and other refs are left as they are
+		return line, nil
+	}
+
+	// floating refs like v1 are tags that are moved on stable releases only, the robot never creates or moves them
+	if floating && isPrerelease(newValue) {
+		return line, nil
+	}
+
+	// the policy is checked against the released version, as the ref with reduced precision lacks the prerelease
+	allowed, err := allowsUpdate(ref, newValue, false, p.versionCompare, p.policy)
+	if err != nil || !allowed {
+		return line, err
+	}
+
+	return prefix + quote + target + "@" + newRef + quote + suffix, nil
+}
+
+// pinnedComment returns the version in the trailing comment of a pinned reference and the suffix with the version
+// replaced by the new value. Other text in the comment is kept, a comment is only added if there is none, such that
+// annotations of other tools (e.g. # renovate: ...) are not changed.
+func pinnedComment(suffix, newValue string) (string, string) {
+	before, comment, ok := strings.Cut(suffix, "#")
+	if !ok {
+		return "", strings.TrimRight(suffix, " \t") + " # " + newValue
+	}
+
+	loc := commentVersionRegex.FindStringSubmatchIndex(comment)
+	if loc == nil {
+		return "", suffix
+	}
+
+	return comment[loc[2]:loc[3]], before + "#" + comment[:loc[2]] + newValue + comment[loc[3]:]
+}
+
+// refWithPrecision returns the new version with the same precision as the old ref, such that floating major
+// version refs like v1 stay floating. It also returns whether the ref is floating.
+func refWithPrecision(ref, newValue string) (string, bool, bool) {
+	if _, err := semver.NewVersion(strings.TrimPrefix(ref, "v")); err != nil {
+		return "", false, false
+	}
+
+	version, err := semver.NewVersion(strings.TrimPrefix(newValue, "v"))
+	if err != nil {
+		return "", false, false
+	}
+
+	prefix := ""
+	if strings.HasPrefix(ref, "v") {
+		prefix = "v"
+	}
+
+	switch strings.Count(ref, ".") {
+	case 0:
+		return fmt.Sprintf("%s%d", prefix, version.Major()), true, true
+	case 1:
+		return fmt.Sprintf("%s%d.%d", prefix, version.Major(), version.Minor()), true, true
+	default:
+		return prefix + strings.TrimPrefix(newValue, "v"), false, true
+	}
+}
+
+func isPrerelease(value string) bool {
+	version, err := semver.NewVersion(strings.TrimPrefix(value, "v"))
+	return err == nil && version.Prerelease() != ""
+}
+
+func (p ActionsUsesPatch) Validate() error {
+	if len(strings.Split(p.repository, "/")) != 2 {
+		return fmt.Errorf("repository must be specified as owner/repo")
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package filepatchers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-robot/pkg/config"
+	"github.com/metal-stack/metal-robot/pkg/utils"
+)
+
+func TestActionsUsesPatch_ApplyRepository(t *testing.T) {
+	const sha = "0123456789abcdef0123456789abcdef01234567"
+
+	workflow := `name: build
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - uses: metal-stack/action-docker-make@v1
+      - uses: metal-stack/action-docker-make/sub@v1.2
+        with:
+          args: --summary
+      - name: pinned
+        uses: "metal-stack/action-docker-make@0000000000000000000000000000000000000000" # v1.2.0
+      - uses: metal-stack/action-docker-make@main
+`
+	action := `runs:
+  using: composite
+  steps:
+    - uses: metal-stack/action-docker-make@v1.2.0
+`
+
+	prereleaseWorkflow := "      - uses: metal-stack/action-docker-make@v1\n" +
+		"      - uses: metal-stack/action-docker-make@v1.2\n" +
+		"      - uses: metal-stack/action-docker-make@v1.2.3\n"
+
+	tests := []struct {
+		name     string
+		p        ActionsUsesPatch
+		files    testRepository
+		newValue string
+		want     testRepository
+		wantErr  bool
+	}{
+		{
+			name: "update floating and exact refs",
+			p:    ActionsUsesPatch{repository: "metal-stack/action-docker-make", versionCompare: true},
+			files: testRepository{
+				".github/workflows/build.yaml": workflow,
+				"action.yml":                   action,
+				"docs/example.yaml":            "uses: metal-stack/action-docker-make@v1\n",
+			},
+			newValue: "v2.0.1",
+			want: testRepository{
+				".github/workflows/build.yaml": `name: build
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - uses: metal-stack/action-docker-make@v2
+      - uses: metal-stack/action-docker-make/sub@v2.0
+        with:
+          args: --summary
+      - name: pinned
+        uses: "metal-stack/action-docker-make@0000000000000000000000000000000000000000" # v1.2.0
+      - uses: metal-stack/action-docker-make@main
+`,
+				"action.yml": `runs:
+  using: composite
+  steps:
+    - uses: metal-stack/action-docker-make@v2.0.1
+`,
+				"docs/example.yaml": "uses: metal-stack/action-docker-make@v1\n",
+			},
+		},
+		{
+			name: "update pinned refs",
+			p:    ActionsUsesPatch{repository: "Metal-Stack/action-docker-make", updatePinned: true, versionCompare: true},
+			files: testRepository{
+				".github/workflows/build.yaml": "      - uses: metal-stack/action-docker-make@0000000000000000000000000000000000000000 # v1.2.0\n",
+			},
+			newValue: "v1.3.0",
+			want: testRepository{
+				".github/workflows/build.yaml": "      - uses: metal-stack/action-docker-make@" + sha + " # v1.3.0\n",
+			},
+		},
+		{
+			name: "update pinned refs and keep comment text",
+			p:    ActionsUsesPatch{repository: "metal-stack/action-docker-make", updatePinned: true, versionCompare: true},
+			files: testRepository{
+				".github/workflows/build.yaml": "      - uses: metal-stack/action-docker-make@0000000000000000000000000000000000000000  # v1.2.0 pinned for CVE-2024-1234\n" +
+					"      - uses: metal-stack/action-docker-make@0000000000000000000000000000000000000000 # renovate: datasource=github-tags\n" +
+					"      - uses: metal-stack/action-docker-make@0000000000000000000000000000000000000000\n",
+			},
+			newValue: "v1.3.0",
+			want: testRepository{
+				".github/workflows/build.yaml": "      - uses: metal-stack/action-docker-make@" + sha + "  # v1.3.0 pinned for CVE-2024-1234\n" +
+					"      - uses: metal-stack/action-docker-make@" + sha + " # renovate: datasource=github-tags\n" +
+					"      - uses: metal-stack/action-docker-make@" + sha + " # v1.3.0\n",
+			},
+		},
+		{
+			name: "change nothing on lower version",
+			p:    ActionsUsesPatch{repository: "metal-stack/action-docker-make", updatePinned: true, versionCompare: true},
+			files: testRepository{
+				"action.yml":                   action,
+				".github/workflows/build.yaml": "      - uses: metal-stack/action-docker-make@0000000000000000000000000000000000000000 # v1.2.0\n",
+			},
+			newValue: "v1.1.0",
+			want: testRepository{
+				"action.yml":                   action,
+				".github/workflows/build.yaml": "      - uses: metal-stack/action-docker-make@0000000000000000000000000000000000000000 # v1.2.0\n",
+			},
+		},
+		{
+			name: "change nothing on prerelease skipped by the version policy",
+			p:    ActionsUsesPatch{repository: "metal-stack/action-docker-make", versionCompare: true, policy: versionPolicy(t, config.VersionPolicyConfig{Prerelease: new(utils.PrereleaseSkip)})},
+			files: testRepository{
+				".github/workflows/build.yaml": prereleaseWorkflow,
+			},
+			newValue: "v2.0.0-rc.1",
+			want: testRepository{
+				".github/workflows/build.yaml": prereleaseWorkflow,
+			},
+		},
+		{
+			name: "allowed prerelease only updates exact refs",
+			p:    ActionsUsesPatch{repository: "metal-stack/action-docker-make", versionCompare: true},
+			files: testRepository{
+				".github/workflows/build.yaml": prereleaseWorkflow,
+			},
+			newValue: "v2.0.0-rc.1",
+			want: testRepository{
+				".github/workflows/build.yaml": "      - uses: metal-stack/action-docker-make@v1\n" +
+					"      - uses: metal-stack/action-docker-make@v1.2\n" +
+					"      - uses: metal-stack/action-docker-make@v2.0.0-rc.1\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.p.resolveTag = func(repoURL, tag string) (string, error) {
+				if repoURL != "https://github.com/"+tt.p.repository+".git" {
+					return "", fmt.Errorf("unexpected repository url: %s", repoURL)
+				}
+				return sha, nil
+			}
+			if err := tt.p.ApplyRepository(context.Background(), slog.New(slog.DiscardHandler), tt.files, tt.newValue, utils.NewTemplateData(tt.newValue)); (err != nil) != tt.wantErr {
+				t.Errorf("ActionsUsesPatch.ApplyRepository() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, tt.files); diff != "" {
+				t.Errorf("ActionsUsesPatch.ApplyRepository() diff: %v", diff)
+			}
+		})
+	}
+}
+
+func TestNewActionsUsesPatch_SkipsPrereleasesByDefault(t *testing.T) {
+	p, err := newActionsUsesPatch(map[string]any{"repository": "metal-stack/action-docker-make"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := testRepository{".github/workflows/build.yaml": "      - uses: metal-stack/action-docker-make@v1.2.3\n"}
+
+	if err := p.ApplyRepository(context.Background(), slog.New(slog.DiscardHandler), repo, "v1.3.0-rc.1", utils.NewTemplateData("v1.3.0-rc.1")); err != nil {
+		t.Fatalf("ActionsUsesPatch.ApplyRepository() error = %v", err)
+	}
+
+	if diff := cmp.Diff(testRepository{".github/workflows/build.yaml": "      - uses: metal-stack/action-docker-make@v1.2.3\n"}, repo); diff != "" {
+		t.Errorf("ActionsUsesPatch.ApplyRepository() diff: %v", diff)
+	}
+}
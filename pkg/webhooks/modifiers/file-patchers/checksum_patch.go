@@ -0,0 +1,355 @@
+package filepatchers
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/metal-stack/metal-robot/pkg/config"
+	"github.com/metal-stack/metal-robot/pkg/utils"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	defaultChecksumMaxSizeMB = 256
+	// maxChecksumsFileSize limits the size of checksums files, which only contain a line per asset
+	maxChecksumsFileSize    = 1024 * 1024
+	checksumDownloadTimeout = 5 * time.Minute
+)
+
+// ChecksumPatch writes the sha256 checksum of a release asset to a yaml path. If a version path is configured, the
+// checksum is only written if the version in the file is not greater than the released version.
+type ChecksumPatch struct {
+	file           string
+	yamlPath       string
+	url            string
+	checksumsURL   *string
+	template       *string
+	maxSize        int64
+	versionPath    *string
+	versionCompare bool
+	policy         *utils.VersionPolicy
+
+	client *http.Client
+}
+
+type checksumCacheKey struct{}
+
+// checksumCache holds the checksums of the assets that were already looked up, such that every asset is downloaded only once
+type checksumCache struct {
+	mu      sync.Mutex
+	entries map[string]*checksumCacheEntry
+}
+
+type checksumCacheEntry struct {
+	once     sync.Once
+	checksum string
+	err      error
+}
+
+// WithChecksumCache returns a context in which the checksum-patch modifier caches the checksums of the assets, it is
+// intended to be used for a single handler run as the assets of a release do not change in the meantime.
+func WithChecksumCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, checksumCacheKey{}, &checksumCache{entries: map[string]*checksumCacheEntry{}})
+}
+
+// cachedChecksum returns the checksum from the cache of the context, concurrent lookups of the same key wait for the first one
+func cachedChecksum(ctx context.Context, key string, lookup func() (string, error)) (string, error) {
+	c, ok := ctx.Value(checksumCacheKey{}).(*checksumCache)
+	if !ok {
+		return lookup()
+	}
+
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if !ok {
+		e = &checksumCacheEntry{}
+		c.entries[key] = e
+	}
+	c.mu.Unlock()
+
+	e.once.Do(func() {
+		e.checksum, e.err = lookup()
+	})
+
+	return e.checksum, e.err
+}
+
+func newChecksumPatch(rawConfig map[string]any) (*ChecksumPatch, error) {
+	var typedConfig config.ChecksumPatchConfig
+	err := mapstructure.Decode(rawConfig, &typedConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	p := ChecksumPatch{
+		file:           typedConfig.File,
+		yamlPath:       typedConfig.YAMLPath,
+		url:            typedConfig.URL,
+		checksumsURL:   typedConfig.ChecksumsURL,
+		template:       typedConfig.Template,
+		maxSize:        defaultChecksumMaxSizeMB * 1024 * 1024,
+		versionPath:    typedConfig.VersionPath,
+		versionCompare: true,
+		client:         &http.Client{},
+	}
+
+	if typedConfig.MaxSizeMB != nil {
+		p.maxSize = *typedConfig.MaxSizeMB * 1024 * 1024
+	}
+
+	if typedConfig.VersionCompare != nil {
+		p.versionCompare = *typedConfig.VersionCompare
+	}
+
+	p.policy, err = utils.NewVersionPolicy(typedConfig.VersionPolicy, utils.PrereleaseAllow)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version policy: %w", err)
+	}
+
+	err = p.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (p ChecksumPatch) Apply(_ ContentReader, _ ContentWriter, _ string, _ utils.TemplateData) error {
+	return fmt.Errorf("downloading release assets requires access to the repository")
+}
+
+// ApplyRepository writes the checksum of the release asset to the file, the asset is downloaded with the context of the handler.
+func (p ChecksumPatch) ApplyRepository(ctx context.Context, _ *slog.Logger, repo Repository, newValue string, data utils.TemplateData) error {
+	content, err := repo.ReadFile(ctx, p.file)
+	if err != nil {
+		return fmt.Errorf("error reading patch file %w", err)
+	}
+
+	old, err := GetYAML(content, p.yamlPath)
+	if err != nil {
+		return fmt.Errorf("error retrieving yaml path from file %w", err)
+	}
+
+	allowed, err := p.isAllowed(content, newValue)
+	if err != nil {
+		return err
+	}
+
+	if !allowed {
+		return nil
+	}
+
+	assetURL, err := renderTemplate(p.url, newValue, "", data)
+	if err != nil {
+		return err
+	}
+
+	checksum, err := p.checksum(ctx, assetURL, newValue, data)
+	if err != nil {
+		return err
+	}
+
+	if p.template != nil {
+		checksum, err = renderTemplate(*p.template, checksum, old, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	if checksum == old {
+		return nil
+	}
+
+	content, err = setYAMLNode(content, p.yamlPath, checksum)
+	if err != nil {
+		return err
+	}
+
+	err = repo.WriteFile(ctx, p.file, content)
+	if err != nil {
+		return fmt.Errorf("error writing patch file %w", err)
+	}
+
+	return nil
+}
+
+// isAllowed checks the released version against the version in the file and the version policy. Equal versions are
+// allowed, such that the version can be patched by a preceding modifier.
+func (p ChecksumPatch) isAllowed(content []byte, newValue string) (bool, error) {
+	if p.versionPath == nil {
+		return isAllowedVersion("", newValue, false, p.policy)
+	}
+
+	old, err := GetYAML(content, *p.versionPath)
+	if err != nil {
+		return false, fmt.Errorf("error retrieving version path from file %w", err)
+	}
+
+	oldVersion, newVersion, err := parseVersions(old, newValue, true)
+	if err != nil {
+		return false, err
+	}
+
+	if oldVersion != nil && oldVersion.Equal(newVersion) {
+		return isAllowedVersion("", newValue, false, p.policy)
+	}
+
+	return allowsUpdate(old, newValue, true, p.versionCompare, p.policy)
+}
+
+// checksum returns the sha256 checksum of the asset, which is looked up in the checksums file if configured
+func (p ChecksumPatch) checksum(ctx context.Context, assetURL, newValue string, data utils.TemplateData) (string, error) {
+	if p.checksumsURL == nil {
+		return cachedChecksum(ctx, assetURL, func() (string, error) {
+			return p.computeChecksum(ctx, assetURL)
+		})
+	}
+
+	checksumsURL, err := renderTemplate(*p.checksumsURL, newValue, "", data)
+	if err != nil {
+		return "", err
+	}
+
+	return cachedChecksum(ctx, checksumsURL+" "+assetURL, func() (string, error) {
+		return p.lookupChecksum(ctx, checksumsURL, assetName(assetURL))
+	})
+}
+
+// computeChecksum downloads the asset and returns its sha256 checksum
+func (p ChecksumPatch) computeChecksum(ctx context.Context, assetURL string) (string, error) {
+	var checksum string
+
+	err := p.download(ctx, assetURL, func(body io.Reader) error {
+		h := sha256.New()
+
+		n, err := io.Copy(h, io.LimitReader(body, p.maxSize+1))
+		if err != nil {
+			return fmt.Errorf("error downloading %s: %w", assetURL, err)
+		}
+
+		if n > p.maxSize {
+			return fmt.Errorf("asset %s exceeds the maximum size of %d bytes", assetURL, p.maxSize)
+		}
+
+		checksum = hex.EncodeToString(h.Sum(nil))
+
+		return nil
+	})
+
+	return checksum, err
+}
+
+// lookupChecksum downloads the checksums file and returns the checksum of the asset
+func (p ChecksumPatch) lookupChecksum(ctx context.Context, checksumsURL, name string) (string, error) {
+	var checksum string
+
+	err := p.download(ctx, checksumsURL, func(body io.Reader) error {
+		var err error
+		checksum, err = findChecksum(body, checksumsURL, name)
+		return err
+	})
+
+	return checksum, err
+}
+
+// findChecksum finds the checksum of the asset in a checksums file in the format of sha256sum
+func findChecksum(body io.Reader, checksumsURL, name string) (string, error) {
+	scanner := bufio.NewScanner(io.LimitReader(body, maxChecksumsFileSize))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+
+		// sha256sum marks files that were read in binary mode with a leading asterisk
+		if strings.TrimPrefix(fields[1], "*") != name {
+			continue
+		}
+
+		checksum := strings.ToLower(fields[0])
+		if _, err := hex.DecodeString(checksum); err != nil || len(checksum) != sha256.Size*2 {
+			return "", fmt.Errorf("invalid sha256 checksum for %s in %s", name, checksumsURL)
+		}
+
+		return checksum, nil
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("error reading %s: %w", checksumsURL, err)
+	}
+
+	return "", fmt.Errorf("no checksum for %s found in %s", name, checksumsURL)
+}
+
+// download passes the body of the given url to the read function, the download is canceled with the given context
+func (p ChecksumPatch) download(ctx context.Context, rawURL string, read func(body io.Reader) error) error {
+	ctx, cancel := context.WithTimeout(ctx, checksumDownloadTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return fmt.Errorf("invalid url %s: %w", rawURL, err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error downloading %s: %w", rawURL, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error downloading %s: unexpected status %s", rawURL, resp.Status)
+	}
+
+	if resp.ContentLength > p.maxSize {
+		return fmt.Errorf("asset %s exceeds the maximum size of %d bytes", rawURL, p.maxSize)
+	}
+
+	return read(resp.Body)
+}
+
+// assetName returns the file name of the asset, which is used in checksums files
+func assetName(assetURL string) string {
+	if u, err := url.Parse(assetURL); err == nil {
+		return path.Base(u.Path)
+	}
+	return path.Base(assetURL)
+}
+
+func (p ChecksumPatch) Validate() error {
+	if p.file == "" {
+		return fmt.Errorf("file must be specified")
+	}
+	if p.yamlPath == "" {
+		return fmt.Errorf("yaml-path must be specified")
+	}
+	if p.url == "" {
+		return fmt.Errorf("url must be specified")
+	}
+	if p.maxSize <= 0 {
+		return fmt.Errorf("max-size-mb must be positive")
+	}
+	if err := validateTemplate("url", &p.url); err != nil {
+		return err
+	}
+	if err := validateTemplate("checksums-url", p.checksumsURL); err != nil {
+		return err
+	}
+	if err := validateTemplate("template", p.template); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package filepatchers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-robot/pkg/config"
+	"github.com/metal-stack/metal-robot/pkg/utils"
+)
+
+func TestChecksumPatch_Apply(t *testing.T) {
+	asset := []byte("metalctl binary")
+	sum := sha256.Sum256(asset)
+	checksum := hex.EncodeToString(sum[:])
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v0.4.0/metalctl-linux-amd64", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(asset)
+	})
+	mux.HandleFunc("/v0.4.0/checksums.txt", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "%x  metalctl-darwin-amd64\n%s *metalctl-linux-amd64\n", sha256.Sum256([]byte("other")), checksum)
+	})
+	mux.HandleFunc("/v0.4.0/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "2048")
+		_, _ = w.Write(make([]byte, 2048))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	input := `binaries:
+  metalctl:
+    version: v0.3.0
+    checksum: ""
+`
+	patchedVersion := strings.Replace(input, "version: v0.3.0", "version: v0.4.0", 1)
+
+	tests := []struct {
+		name     string
+		p        ChecksumPatch
+		input    *string
+		newValue string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name: "download asset and compute checksum",
+			p: ChecksumPatch{
+				file:     "release.yaml",
+				yamlPath: "binaries.metalctl.checksum",
+				url:      server.URL + "/%s/metalctl-linux-amd64",
+				maxSize:  1024,
+			},
+			newValue: "v0.4.0",
+			want: fmt.Sprintf(`binaries:
+  metalctl:
+    version: v0.3.0
+    checksum: "%s"
+`, checksum),
+		},
+		{
+			name: "read checksum from checksums file with template",
+			p: ChecksumPatch{
+				file:         "release.yaml",
+				yamlPath:     "binaries.metalctl.checksum",
+				url:          server.URL + "/{{ .Tag }}/metalctl-linux-amd64",
+				checksumsURL: new(server.URL + "/{{ .Tag }}/checksums.txt"),
+				template:     new("sha256:%s"),
+				maxSize:      1024,
+			},
+			newValue: "v0.4.0",
+			want: fmt.Sprintf(`binaries:
+  metalctl:
+    version: v0.3.0
+    checksum: "sha256:%s"
+`, checksum),
+		},
+		{
+			name: "update checksum of version that was patched before",
+			p: ChecksumPatch{
+				file:           "release.yaml",
+				yamlPath:       "binaries.metalctl.checksum",
+				url:            server.URL + "/%s/metalctl-linux-amd64",
+				maxSize:        1024,
+				versionPath:    new("binaries.metalctl.version"),
+				versionCompare: true,
+			},
+			input:    &patchedVersion,
+			newValue: "v0.4.0",
+			want:     strings.Replace(patchedVersion, `checksum: ""`, fmt.Sprintf(`checksum: "%s"`, checksum), 1),
+		},
+		{
+			name: "change nothing on lower version",
+			p: ChecksumPatch{
+				file:           "release.yaml",
+				yamlPath:       "binaries.metalctl.checksum",
+				url:            server.URL + "/%s/metalctl-linux-amd64",
+				maxSize:        1024,
+				versionPath:    new("binaries.metalctl.version"),
+				versionCompare: true,
+			},
+			newValue: "v0.2.0",
+			want:     "",
+		},
+		{
+			name: "change nothing on prerelease skipped by the version policy",
+			p: ChecksumPatch{
+				file:     "release.yaml",
+				yamlPath: "binaries.metalctl.checksum",
+				url:      server.URL + "/%s/metalctl-linux-amd64",
+				maxSize:  1024,
+				policy:   versionPolicy(t, config.VersionPolicyConfig{Prerelease: new(utils.PrereleaseSkip)}),
+			},
+			newValue: "v0.5.0-rc.1",
+			want:     "",
+		},
+		{
+			name: "asset not in checksums file",
+			p: ChecksumPatch{
+				file:         "release.yaml",
+				yamlPath:     "binaries.metalctl.checksum",
+				url:          server.URL + "/%s/metalctl-windows-amd64",
+				checksumsURL: new(server.URL + "/%s/checksums.txt"),
+				maxSize:      1024,
+			},
+			newValue: "v0.4.0",
+			wantErr:  true,
+		},
+		{
+			name: "asset exceeds maximum size",
+			p: ChecksumPatch{
+				file:     "release.yaml",
+				yamlPath: "binaries.metalctl.checksum",
+				url:      server.URL + "/%s/large",
+				maxSize:  1024,
+			},
+			newValue: "v0.4.0",
+			wantErr:  true,
+		},
+		{
+			name: "asset not found",
+			p: ChecksumPatch{
+				file:     "release.yaml",
+				yamlPath: "binaries.metalctl.checksum",
+				url:      server.URL + "/%s/metalctl-linux-arm64",
+				maxSize:  1024,
+			},
+			newValue: "v0.4.0",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.p.client = server.Client()
+
+			content := input
+			if tt.input != nil {
+				content = *tt.input
+			}
+
+			repo := testRepository{"release.yaml": content}
+			if err := tt.p.ApplyRepository(context.Background(), slog.New(slog.DiscardHandler), repo, tt.newValue, utils.NewTemplateData(tt.newValue)); (err != nil) != tt.wantErr {
+				t.Errorf("ChecksumPatch.ApplyRepository() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			// an unchanged file is expected as empty
+			got := repo["release.yaml"]
+			if got == content {
+				got = ""
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ChecksumPatch.ApplyRepository() diff: %v", diff)
+			}
+		})
+	}
+}
+
+func TestChecksumPatch_ApplyRepository_Download(t *testing.T) {
+	var downloads atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads.Add(1)
+		_, _ = w.Write([]byte("metalctl binary"))
+	}))
+	defer server.Close()
+
+	p := ChecksumPatch{
+		file:     "release.yaml",
+		yamlPath: "checksum",
+		url:      server.URL + "/%s/metalctl-linux-amd64",
+		maxSize:  1024,
+		client:   server.Client(),
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		wantDownloads int32
+		wantErr       bool
+	}{
+		{
+			name:          "without cache every run downloads the asset",
+			ctx:           context.Background(),
+			wantDownloads: 2,
+		},
+		{
+			name:          "with cache the asset is downloaded once",
+			ctx:           WithChecksumCache(context.Background()),
+			wantDownloads: 1,
+		},
+		{
+			name:          "canceled context",
+			ctx:           canceled,
+			wantDownloads: 0,
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			downloads.Store(0)
+
+			for range 2 {
+				repo := testRepository{"release.yaml": "checksum: \"\"\n"}
+				if err := p.ApplyRepository(tt.ctx, slog.New(slog.DiscardHandler), repo, "v0.4.0", utils.NewTemplateData("v0.4.0")); (err != nil) != tt.wantErr {
+					t.Errorf("ChecksumPatch.ApplyRepository() error = %v, wantErr %v", err, tt.wantErr)
+				}
+			}
+
+			if got := downloads.Load(); got != tt.wantDownloads {
+				t.Errorf("ChecksumPatch.ApplyRepository() downloads = %d, want %d", got, tt.wantDownloads)
+			}
+		})
+	}
+}
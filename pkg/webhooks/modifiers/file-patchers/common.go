@@ -1,31 +1,205 @@
 package filepatchers
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"strings"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/metal-stack/metal-robot/pkg/config"
+	"github.com/metal-stack/metal-robot/pkg/utils"
 )
 
 const (
-	LinePatchModifierName       string = "line-patch"
-	YAMLPathVersionModifierName string = "yaml-path-version-patch"
+	LinePatchModifierName           string = "line-patch"
+	YAMLPathVersionModifierName     string = "yaml-path-version-patch"
+	RegexPatchModifierName          string = "regex-patch"
+	JSONPathPatchModifierName       string = "json-path-patch"
+	TOMLPathPatchModifierName       string = "toml-path-patch"
+	GoModPatchModifierName          string = "go-mod-patch"
+	DockerfilePatchModifierName     string = "dockerfile-patch"
+	HelmChartPatchModifierName      string = "helm-chart-patch"
+	KustomizeImagePatchModifierName string = "kustomize-image-patch"
+	ActionsUsesPatchModifierName    string = "actions-uses-patch"
+	SubmodulePatchModifierName      string = "submodule-patch"
+	ExecPatchModifierName           string = "exec-patch"
+	ChecksumPatchModifierName       string = "checksum-patch"
 )
 
 type ContentReader func(file string) ([]byte, error)
 type ContentWriter func(file string, content []byte) error
 
 type Patcher interface {
-	Apply(cr ContentReader, cw ContentWriter, newValue string) error
+	Apply(cr ContentReader, cw ContentWriter, newValue string, data utils.TemplateData) error
 	Validate() error
 }
 
-func InitPatcher(c config.Modifier) (Patcher, error) {
+// Repository gives access to the files of the repository that is patched.
+type Repository interface {
+	ReadFile(ctx context.Context, path string) ([]byte, error)
+	WriteFile(ctx context.Context, path string, data []byte) error
+	ListFiles(ctx context.Context, dir string) ([]string, error)
+}
+
+// SubmoduleRepository is implemented by repositories that can move the commit a submodule points to.
+type SubmoduleRepository interface {
+	Repository
+	ReadSubmodule(path string) (string, error)
+	WriteSubmodule(path, commit string) error
+}
+
+// RepositoryPatcher is implemented by patchers that do not know their target files upfront and need to discover them.
+// The context and the logger are the ones of the handler that applies the patcher.
+type RepositoryPatcher interface {
+	Patcher
+	ApplyRepository(ctx context.Context, log *slog.Logger, repo Repository, newValue string, data utils.TemplateData) error
+}
+
+// Options are the settings of the robot that apply to all modifiers.
+type Options struct {
+	// ExecAllowedCommands are the commands that exec-patch modifiers may run, by default no command is allowed.
+	ExecAllowedCommands []string
+}
+
+// Apply applies the patcher to the given repository, the data is passed to the templates of the patcher.
+func Apply(ctx context.Context, log *slog.Logger, p Patcher, repo Repository, newValue string, data utils.TemplateData) error {
+	if rp, ok := p.(RepositoryPatcher); ok {
+		return rp.ApplyRepository(ctx, log, repo, newValue, data)
+	}
+	cr := func(file string) ([]byte, error) {
+		return repo.ReadFile(ctx, file)
+	}
+	cw := func(file string, content []byte) error {
+		return repo.WriteFile(ctx, file, content)
+	}
+
+	return p.Apply(cr, cw, newValue, data)
+}
+
+// InitPatcher initializes the patcher of the given modifier. In case the file of the modifier is a glob pattern, the patcher
+// is applied to all matching files.
+func InitPatcher(c config.Modifier, opts Options) (Patcher, error) {
+	p, err := initPatcher(c, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return withGlob(p, c.Args)
+}
+
+func initPatcher(c config.Modifier, opts Options) (Patcher, error) {
 	switch t := c.Type; t {
 	case YAMLPathVersionModifierName:
 		return newYAMLPathPatch(c.Args)
 	case LinePatchModifierName:
 		return newLinePatch(c.Args)
+	case RegexPatchModifierName:
+		return newRegexPatch(c.Args)
+	case JSONPathPatchModifierName:
+		return newJSONPathPatch(c.Args)
+	case TOMLPathPatchModifierName:
+		return newTOMLPathPatch(c.Args)
+	case GoModPatchModifierName:
+		return newGoModPatch(c.Args)
+	case DockerfilePatchModifierName:
+		return newDockerfilePatch(c.Args)
+	case HelmChartPatchModifierName:
+		return newHelmChartPatch(c.Args)
+	case KustomizeImagePatchModifierName:
+		return newKustomizeImagePatch(c.Args)
+	case ActionsUsesPatchModifierName:
+		return newActionsUsesPatch(c.Args)
+	case SubmodulePatchModifierName:
+		return newSubmodulePatch(c.Args)
+	case ExecPatchModifierName:
+		return newExecPatch(c.Args, opts.ExecAllowedCommands)
+	case ChecksumPatchModifierName:
+		return newChecksumPatch(c.Args)
 	default:
 		return nil, fmt.Errorf("unsupported modifier type: %v", t)
 	}
 }
+
+// renderTemplate renders the template of a patcher. Templates with a %s verb are formatted with the new value.
+func renderTemplate(tpl, newValue, oldValue string, data utils.TemplateData) (string, error) {
+	data.Value = newValue
+	data.OldValue = oldValue
+
+	return utils.RenderTemplate(tpl, data, newValue)
+}
+
+// validateTemplate checks that the given template of a patcher can be parsed, such that errors are found on startup
+// instead of on the first release.
+func validateTemplate(name string, tpl *string) error {
+	if tpl == nil {
+		return nil
+	}
+
+	err := utils.ValidateTemplate(*tpl)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// allowsUpdate returns true if the old value is updated to the new value. With version comparison, the new value needs
+// to be a newer version, otherwise only the version policy is checked.
+func allowsUpdate(old, newValue string, templated, versionCompare bool, policy *utils.VersionPolicy) (bool, error) {
+	if versionCompare {
+		return isNewerVersion(old, newValue, templated, policy)
+	}
+	return isAllowedVersion(old, newValue, templated, policy)
+}
+
+// isNewerVersion returns true if the new value is a greater semantic version than the old value. When the old value
+// was rendered from a template, the version is extracted from it first. If the old value does not contain a semantic
+// version, the new value is always considered to be newer. In addition, the new version needs to be allowed by the version policy.
+func isNewerVersion(old, newValue string, templated bool, policy *utils.VersionPolicy) (bool, error) {
+	oldVersion, newVersion, err := parseVersions(old, newValue, templated)
+	if err != nil {
+		return false, err
+	}
+
+	if oldVersion != nil && !newVersion.GreaterThan(oldVersion) {
+		return false, nil
+	}
+
+	return policy.Allows(oldVersion, newVersion) == nil, nil
+}
+
+// isAllowedVersion returns true if the version policy allows updating from the old to the new value. In contrast to
+// isNewerVersion, the new value does not need to be greater than the old value.
+func isAllowedVersion(old, newValue string, templated bool, policy *utils.VersionPolicy) (bool, error) {
+	if policy == nil {
+		return true, nil
+	}
+
+	oldVersion, newVersion, err := parseVersions(old, newValue, templated)
+	if err != nil {
+		return false, err
+	}
+
+	return policy.Allows(oldVersion, newVersion) == nil, nil
+}
+
+// parseVersions parses the old and the new value as semantic versions, the old version is nil if it does not contain a semantic version.
+func parseVersions(old, newValue string, templated bool) (*semver.Version, *semver.Version, error) {
+	newVersion, err := semver.NewVersion(strings.TrimPrefix(newValue, "v"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if templated {
+		groups := utils.RegexCapture(utils.SemanticVersionMatcher, old)
+		old = groups["full_match"]
+	}
+
+	oldVersion, err := semver.NewVersion(strings.TrimPrefix(old, "v"))
+	if err != nil {
+		return nil, newVersion, nil
+	}
+
+	return oldVersion, newVersion, nil
+}
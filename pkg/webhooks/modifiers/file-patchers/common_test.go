@@ -0,0 +1,38 @@
+package filepatchers
+
+import (
+	"testing"
+
+	"github.com/metal-stack/metal-robot/pkg/config"
+)
+
+func TestInitPatcher_InvalidTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		modifier config.Modifier
+	}{
+		{
+			name:     "line patch",
+			modifier: config.Modifier{Type: LinePatchModifierName, Args: map[string]any{"file": "README.md", "line": 1, "template": "version {{ .Tag }"}},
+		},
+		{
+			name:     "yaml path patch",
+			modifier: config.Modifier{Type: YAMLPathVersionModifierName, Args: map[string]any{"file": "release.yaml", "yaml-path": "version", "template": "{{ .Tag"}},
+		},
+		{
+			name:     "kustomize image patch",
+			modifier: config.Modifier{Type: KustomizeImagePatchModifierName, Args: map[string]any{"file": "kustomization.yaml", "image": "metal-api", "template": "{{ end }}"}},
+		},
+		{
+			name:     "checksum patch url",
+			modifier: config.Modifier{Type: ChecksumPatchModifierName, Args: map[string]any{"file": "release.yaml", "yaml-path": "checksum", "url": "https://example.com/{{ .Tag }/metalctl"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := InitPatcher(tt.modifier, Options{}); err == nil {
+				t.Errorf("InitPatcher() expected an error for an invalid template")
+			}
+		})
+	}
+}
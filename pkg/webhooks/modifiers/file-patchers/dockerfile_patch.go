@@ -0,0 +1,235 @@
+package filepatchers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/metal-stack/metal-robot/pkg/config"
+	"github.com/metal-stack/metal-robot/pkg/utils"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	defaultDockerfile = "Dockerfile"
+)
+
+var (
+	dockerfileFromRegex = regexp.MustCompile(`(?i)^(\s*FROM\s+(?:--\S+\s+)*)(\S+)(.*)$`)
+	dockerfileArgRegex  = regexp.MustCompile(`(?i)^(\s*ARG\s+)(.*)$`)
+)
+
+type DockerfilePatch struct {
+	file           string
+	image          *string
+	arg            *string
+	template       *string
+	versionCompare bool
+	policy         *utils.VersionPolicy
+}
+
+func newDockerfilePatch(rawConfig map[string]any) (*DockerfilePatch, error) {
+	var typedConfig config.DockerfilePatchConfig
+	err := mapstructure.Decode(rawConfig, &typedConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	p := DockerfilePatch{
+		file:           defaultDockerfile,
+		image:          typedConfig.Image,
+		arg:            typedConfig.Arg,
+		template:       typedConfig.Template,
+		versionCompare: true,
+	}
+
+	if typedConfig.File != nil {
+		p.file = *typedConfig.File
+	}
+
+	if typedConfig.VersionCompare != nil {
+		p.versionCompare = *typedConfig.VersionCompare
+	}
+
+	p.policy, err = utils.NewVersionPolicy(typedConfig.VersionPolicy, utils.PrereleaseAllow)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// Apply updates the tag of all FROM instructions that use the configured image or the default value of all ARG
+// instructions with the configured name. This covers all stages of a multi-stage build.
+func (p DockerfilePatch) Apply(cr ContentReader, cw ContentWriter, newValue string, data utils.TemplateData) error {
+	content, err := cr(p.file)
+	if err != nil {
+		return fmt.Errorf("error reading patch file %w", err)
+	}
+
+	var (
+		lines   = strings.Split(string(content), "\n")
+		found   = false
+		patched = false
+	)
+
+	for i, line := range lines {
+		var (
+			ok      bool
+			updated string
+		)
+
+		if p.image != nil {
+			ok, updated, err = p.patchFrom(line, newValue, data)
+		} else {
+			ok, updated, err = p.patchArg(line, newValue, data)
+		}
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			continue
+		}
+
+		found = true
+
+		if updated != line {
+			lines[i] = updated
+			patched = true
+		}
+	}
+
+	if !found {
+		if p.image != nil {
+			return fmt.Errorf("no FROM instruction with image %s found in %s", *p.image, p.file)
+		}
+		return fmt.Errorf("no ARG instruction with name %s found in %s", *p.arg, p.file)
+	}
+
+	if !patched {
+		return nil
+	}
+
+	err = cw(p.file, []byte(strings.Join(lines, "\n")))
+	if err != nil {
+		return fmt.Errorf("error writing patch file %w", err)
+	}
+
+	return nil
+}
+
+// patchFrom replaces the tag of the image in a FROM instruction. A digest is removed because it pins the old image.
+func (p DockerfilePatch) patchFrom(line, newValue string, data utils.TemplateData) (bool, string, error) {
+	matches := dockerfileFromRegex.FindStringSubmatch(line)
+	if matches == nil {
+		return false, line, nil
+	}
+
+	repository, tag, _ := splitImageReference(matches[2])
+	if repository != *p.image {
+		return false, line, nil
+	}
+
+	newTag, ok, err := p.newValue(tag, newValue, data)
+	if err != nil || !ok {
+		return true, line, err
+	}
+
+	return true, matches[1] + repository + ":" + newTag + matches[3], nil
+}
+
+// patchArg replaces the default value of an ARG instruction, quotes around the value are kept
+func (p DockerfilePatch) patchArg(line, newValue string, data utils.TemplateData) (bool, string, error) {
+	matches := dockerfileArgRegex.FindStringSubmatch(line)
+	if matches == nil {
+		return false, line, nil
+	}
+
+	args := matches[2]
+
+	for start := 0; start < len(args); {
+		if args[start] == ' ' || args[start] == '\t' {
+			start++
+			continue
+		}
+
+		end := strings.IndexAny(args[start:], " \t")
+		if end < 0 {
+			end = len(args)
+		} else {
+			end += start
+		}
+
+		name, value, hasValue := strings.Cut(args[start:end], "=")
+		if name != *p.arg {
+			start = end
+			continue
+		}
+
+		if !hasValue {
+			return false, line, nil
+		}
+
+		quote := ""
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			quote = value[:1]
+			value = value[1 : len(value)-1]
+		}
+
+		newArg, ok, err := p.newValue(value, newValue, data)
+		if err != nil || !ok {
+			return true, line, err
+		}
+
+		return true, matches[1] + args[:start] + name + "=" + quote + newArg + quote + args[end:], nil
+	}
+
+	return false, line, nil
+}
+
+func (p DockerfilePatch) newValue(old, newValue string, data utils.TemplateData) (string, bool, error) {
+	allowed, err := allowsUpdate(old, newValue, p.template != nil, p.versionCompare, p.policy)
+	if err != nil || !allowed {
+		return "", false, err
+	}
+
+	if p.template != nil {
+		newValue, err = renderTemplate(*p.template, newValue, old, data)
+		if err != nil {
+			return "", false, err
+		}
+	}
+
+	return newValue, true, nil
+}
+
+// splitImageReference splits an image reference into repository, tag and digest
+func splitImageReference(ref string) (repository, tag, digest string) {
+	repository, digest, _ = strings.Cut(ref, "@")
+
+	if idx := strings.LastIndex(repository, ":"); idx > strings.LastIndex(repository, "/") {
+		tag = repository[idx+1:]
+		repository = repository[:idx]
+	}
+
+	return repository, tag, digest
+}
+
+func (p DockerfilePatch) Validate() error {
+	if p.file == "" {
+		return fmt.Errorf("file must be specified")
+	}
+	if (p.image == nil) == (p.arg == nil) {
+		return fmt.Errorf("either image or arg must be specified")
+	}
+	if err := validateTemplate("template", p.template); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package filepatchers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-robot/pkg/utils"
+)
+
+func TestDockerfilePatch_Apply(t *testing.T) {
+	input := `ARG BUILDER_VERSION=v1.2.3
+FROM ghcr.io/metal-stack/builder:v1.2.3 AS builder
+ARG METAL_API_VERSION="v0.37.2" DEBUG=false
+RUN make
+
+FROM --platform=linux/amd64 ghcr.io/metal-stack/builder:v1.2.3@sha256:4d1a6b2c3e7f8a9b0c1d2e3f4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3b as test
+RUN make test
+
+from builder
+FROM gcr.io/distroless/static-debian12:nonroot
+ARG METAL_API_VERSION="v0.37.2"
+COPY --from=builder /work/bin/metal-api /metal-api
+`
+	tpl := "%s-alpine"
+
+	tests := []struct {
+		name     string
+		p        DockerfilePatch
+		newValue string
+		input    string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "update image tag in all stages and drop digest",
+			p:        DockerfilePatch{file: "Dockerfile", image: new("ghcr.io/metal-stack/builder"), versionCompare: true},
+			newValue: "v1.3.0",
+			input:    input,
+			want: strings.NewReplacer(
+				"ghcr.io/metal-stack/builder:v1.2.3@sha256:4d1a6b2c3e7f8a9b0c1d2e3f4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3b", "ghcr.io/metal-stack/builder:v1.3.0",
+				"ghcr.io/metal-stack/builder:v1.2.3 AS", "ghcr.io/metal-stack/builder:v1.3.0 AS",
+			).Replace(input),
+		},
+		{
+			name:     "update arg in all stages",
+			p:        DockerfilePatch{file: "Dockerfile", arg: new("METAL_API_VERSION"), versionCompare: true},
+			newValue: "v0.38.0",
+			input:    input,
+			want:     strings.ReplaceAll(input, `METAL_API_VERSION="v0.37.2"`, `METAL_API_VERSION="v0.38.0"`),
+		},
+		{
+			name:     "update unquoted arg",
+			p:        DockerfilePatch{file: "Dockerfile", arg: new("BUILDER_VERSION"), versionCompare: true},
+			newValue: "v1.3.0",
+			input:    input,
+			want:     strings.Replace(input, "ARG BUILDER_VERSION=v1.2.3", "ARG BUILDER_VERSION=v1.3.0", 1),
+		},
+		{
+			name:     "update image tag with template",
+			p:        DockerfilePatch{file: "Dockerfile", image: new("golang"), template: &tpl, versionCompare: true},
+			newValue: "1.23.1",
+			input:    "FROM golang:1.22.5-alpine\n",
+			want:     "FROM golang:1.23.1-alpine\n",
+		},
+		{
+			name:     "change nothing on lower version",
+			p:        DockerfilePatch{file: "Dockerfile", image: new("ghcr.io/metal-stack/builder"), versionCompare: true},
+			newValue: "v1.1.0",
+			input:    input,
+			want:     "",
+		},
+		{
+			name:     "image not found",
+			p:        DockerfilePatch{file: "Dockerfile", image: new("ghcr.io/metal-stack/metal-api"), versionCompare: true},
+			newValue: "v1.1.0",
+			input:    input,
+			wantErr:  true,
+		},
+		{
+			name:     "arg without default is not found",
+			p:        DockerfilePatch{file: "Dockerfile", arg: new("VERSION"), versionCompare: true},
+			newValue: "v1.1.0",
+			input:    "ARG VERSION\n",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			cn := func(file string) ([]byte, error) {
+				return []byte(tt.input), nil
+			}
+			cw := func(file string, content []byte) error {
+				got = string(content)
+				return nil
+			}
+			if err := tt.p.Apply(cn, cw, tt.newValue, utils.NewTemplateData(tt.newValue)); (err != nil) != tt.wantErr {
+				t.Errorf("DockerfilePatch.Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("DockerfilePatch.Apply() diff: %v", diff)
+			}
+		})
+	}
+}
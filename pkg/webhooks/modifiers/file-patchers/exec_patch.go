@@ -0,0 +1,307 @@
+package filepatchers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/metal-stack/metal-robot/pkg/config"
+	"github.com/metal-stack/metal-robot/pkg/utils"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	defaultExecTimeout = time.Minute
+	// execWaitDelay is the time that child processes of a killed command get to release the output pipes
+	execWaitDelay = time.Second
+	// maxExecOutputLength limits the command output that is logged and returned in errors
+	maxExecOutputLength = 4096
+)
+
+// ExecPatch runs an external command on a copy of the repository files and imports the changed files back.
+type ExecPatch struct {
+	command         string
+	args            []string
+	files           []string
+	timeout         time.Duration
+	importNewFiles  bool
+	allowedCommands []string
+}
+
+// execInput is passed to the command as json on stdin
+type execInput struct {
+	Value   string             `json:"value"`
+	Files   []string           `json:"files"`
+	Release utils.TemplateData `json:"release"`
+}
+
+func newExecPatch(rawConfig map[string]any, allowedCommands []string) (*ExecPatch, error) {
+	var typedConfig config.ExecModifierConfig
+	err := mapstructure.Decode(rawConfig, &typedConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	p := ExecPatch{
+		command:         typedConfig.Command,
+		args:            typedConfig.Args,
+		files:           typedConfig.Files,
+		timeout:         defaultExecTimeout,
+		allowedCommands: allowedCommands,
+	}
+
+	if typedConfig.ImportNewFiles != nil {
+		p.importNewFiles = *typedConfig.ImportNewFiles
+	}
+
+	if typedConfig.Timeout != nil {
+		p.timeout, err = time.ParseDuration(*typedConfig.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+	}
+
+	err = p.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (p ExecPatch) Apply(_ ContentReader, _ ContentWriter, _ string, _ utils.TemplateData) error {
+	return fmt.Errorf("running commands requires access to the repository")
+}
+
+// ApplyRepository checks out the files into a temporary directory, runs the command in it and writes back the files
+// that were changed by the command. Files created by the command are only imported if configured, files deleted by the
+// command cannot be removed from the repository and are left as they are.
+func (p ExecPatch) ApplyRepository(ctx context.Context, log *slog.Logger, repo Repository, newValue string, data utils.TemplateData) error {
+	log = log.With("modifier", ExecPatchModifierName, "command", p.command)
+
+	files, err := p.checkoutFiles(ctx, repo)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "metal-robot-exec-patch-")
+	if err != nil {
+		return fmt.Errorf("error creating temporary directory: %w", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	original := map[string][]byte{}
+	for _, file := range files {
+		content, err := repo.ReadFile(ctx, file)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(file))
+
+		err = os.MkdirAll(filepath.Dir(target), 0700)
+		if err != nil {
+			return fmt.Errorf("error checking out %s: %w", file, err)
+		}
+
+		err = os.WriteFile(target, content, 0600)
+		if err != nil {
+			return fmt.Errorf("error checking out %s: %w", file, err)
+		}
+
+		original[file] = content
+	}
+
+	err = p.run(ctx, log, dir, newValue, files, data)
+	if err != nil {
+		return err
+	}
+
+	found := map[string]bool{}
+
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if !filepath.IsLocal(rel) {
+			return fmt.Errorf("file %s is not inside of the checkout", path)
+		}
+		file := filepath.ToSlash(rel)
+
+		// symlinks are neither followed nor imported, such that the command cannot import files from outside of the checkout
+		if !d.Type().IsRegular() {
+			if d.Type()&fs.ModeSymlink != 0 {
+				log.Warn("ignoring symlink created by command", "file", file)
+			}
+			return nil
+		}
+
+		found[file] = true
+
+		before, ok := original[file]
+		if !ok && !p.importNewFiles {
+			log.Warn("ignoring file created by command, import-new-files is disabled", "file", file)
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error importing %s: %w", file, err)
+		}
+
+		if ok && bytes.Equal(before, content) {
+			return nil
+		}
+
+		err = repo.WriteFile(ctx, file, content)
+		if err != nil {
+			return fmt.Errorf("error writing patch file %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if !found[file] {
+			log.Warn("file was deleted by command, deletions are not applied to the repository", "file", file)
+		}
+	}
+
+	return nil
+}
+
+// checkoutFiles returns the files of the repository that match the configured files
+func (p ExecPatch) checkoutFiles(ctx context.Context, repo Repository) ([]string, error) {
+	files, err := repo.ListFiles(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(p.files) == 0 {
+		return files, nil
+	}
+
+	var res []string
+	for _, file := range files {
+		for _, pattern := range p.files {
+			if matchGlob(pattern, file) {
+				res = append(res, file)
+				break
+			}
+		}
+	}
+
+	if len(res) == 0 {
+		return nil, fmt.Errorf("no files match %s", strings.Join(p.files, ", "))
+	}
+
+	return res, nil
+}
+
+func (p ExecPatch) run(ctx context.Context, log *slog.Logger, dir, newValue string, files []string, data utils.TemplateData) error {
+	data.Value = newValue
+
+	var args []string
+	for _, arg := range p.args {
+		if !utils.IsTemplate(arg) {
+			args = append(args, arg)
+			continue
+		}
+
+		rendered, err := utils.RenderTemplate(arg, data)
+		if err != nil {
+			return fmt.Errorf("error rendering command argument: %w", err)
+		}
+		args = append(args, rendered)
+	}
+
+	input, err := json.Marshal(execInput{
+		Value:   newValue,
+		Files:   files,
+		Release: data,
+	})
+	if err != nil {
+		return fmt.Errorf("error encoding command input: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+
+	// the environment of the robot contains credentials, so the command only receives what it needs
+	cmd := exec.CommandContext(ctx, p.command, args...) // nolint:gosec
+	cmd.Dir = dir
+	cmd.Env = []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + dir,
+		"METAL_ROBOT_VALUE=" + newValue,
+		"METAL_ROBOT_TAG=" + data.Tag,
+		"METAL_ROBOT_FILES=" + strings.Join(files, "\n"),
+	}
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = execWaitDelay
+
+	err = cmd.Run()
+
+	output := truncateOutput(stderr.String())
+	if output != "" {
+		log.Info("command wrote to stderr", "stderr", output)
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("command %s did not finish within %s", p.command, p.timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("error running command %s: %w: %s", p.command, err, output)
+	}
+
+	return nil
+}
+
+func truncateOutput(output string) string {
+	output = strings.TrimSpace(output)
+	if len(output) > maxExecOutputLength {
+		return output[:maxExecOutputLength] + "... (truncated)"
+	}
+	return output
+}
+
+func (p ExecPatch) Validate() error {
+	if p.command == "" {
+		return fmt.Errorf("command must be specified")
+	}
+	if !slices.Contains(p.allowedCommands, p.command) {
+		return fmt.Errorf("command %s is not allowed, it needs to be added to the allowed commands of the exec-patch configuration", p.command)
+	}
+	if p.timeout <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+	for _, arg := range p.args {
+		if err := validateTemplate("args", &arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package filepatchers
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-robot/pkg/utils"
+)
+
+func TestExecPatch_ApplyRepository(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("test requires sh")
+	}
+
+	tests := []struct {
+		name     string
+		p        ExecPatch
+		files    testRepository
+		want     testRepository
+		wantLogs []string
+		wantErr  bool
+	}{
+		{
+			name: "import changed files",
+			p: ExecPatch{
+				command: "sh",
+				args:    []string{"-c", `echo "version: $1" > deploy/values.yaml && cat > input.json && echo "regenerated" >&2`, "sh", "{{ .Version }}"},
+				files:   []string{"deploy/*.yaml", "input.json"},
+				timeout: time.Minute,
+			},
+			files: testRepository{
+				"deploy/values.yaml": "version: 0.1.0\n",
+				"input.json":         "",
+				"README.md":          "# readme\n",
+			},
+			want: testRepository{
+				"deploy/values.yaml": "version: 0.2.0\n",
+				"input.json":         `{"value":"v0.2.0","files":["deploy/values.yaml","input.json"],"release":{"value":"v0.2.0","tag":"v0.2.0","version":"0.2.0","major":0,"minor":2,"patch":0,"prerelease":"","repository-name":"","repository-url":"","sender":""}}`,
+				"README.md":          "# readme\n",
+			},
+			wantLogs: []string{"msg=\"command wrote to stderr\" request=1 modifier=exec-patch command=sh stderr=regenerated"},
+		},
+		{
+			name: "created files are ignored by default",
+			p: ExecPatch{
+				command: "sh",
+				args:    []string{"-c", `echo "$METAL_ROBOT_VALUE" > VERSION`},
+				files:   []string{"README.md"},
+				timeout: time.Minute,
+			},
+			files:    testRepository{"README.md": "# readme\n"},
+			want:     testRepository{"README.md": "# readme\n"},
+			wantLogs: []string{"msg=\"ignoring file created by command, import-new-files is disabled\" request=1 modifier=exec-patch command=sh file=VERSION"},
+		},
+		{
+			name: "import created files but no symlinks",
+			p: ExecPatch{
+				command:        "sh",
+				args:           []string{"-c", `mkdir -p docs && echo "$METAL_ROBOT_VALUE" > docs/VERSION && ln -s /etc/hostname hostname`},
+				files:          []string{"README.md"},
+				timeout:        time.Minute,
+				importNewFiles: true,
+			},
+			files: testRepository{"README.md": "# readme\n"},
+			want: testRepository{
+				"README.md":    "# readme\n",
+				"docs/VERSION": "v0.2.0\n",
+			},
+			wantLogs: []string{"msg=\"ignoring symlink created by command\" request=1 modifier=exec-patch command=sh file=hostname"},
+		},
+		{
+			name: "deleted files are kept",
+			p: ExecPatch{
+				command: "sh",
+				args:    []string{"-c", "rm README.md"},
+				timeout: time.Minute,
+			},
+			files:    testRepository{"README.md": "# readme\n"},
+			want:     testRepository{"README.md": "# readme\n"},
+			wantLogs: []string{"msg=\"file was deleted by command, deletions are not applied to the repository\" request=1 modifier=exec-patch command=sh file=README.md"},
+		},
+		{
+			name: "failing command",
+			p: ExecPatch{
+				command: "sh",
+				args:    []string{"-c", `echo "broken" > README.md; exit 1`},
+				timeout: time.Minute,
+			},
+			files:   testRepository{"README.md": "# readme\n"},
+			want:    testRepository{"README.md": "# readme\n"},
+			wantErr: true,
+		},
+		{
+			name: "timeout",
+			p: ExecPatch{
+				command: "sh",
+				args:    []string{"-c", "sleep 5"},
+				timeout: 50 * time.Millisecond,
+			},
+			files:   testRepository{"README.md": "# readme\n"},
+			want:    testRepository{"README.md": "# readme\n"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			log := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{
+				ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+					if a.Key == slog.TimeKey {
+						return slog.Attr{}
+					}
+					return a
+				},
+			})).With("request", 1)
+
+			if err := tt.p.ApplyRepository(context.Background(), log, tt.files, "v0.2.0", utils.NewTemplateData("v0.2.0")); (err != nil) != tt.wantErr {
+				t.Errorf("ExecPatch.ApplyRepository() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, tt.files); diff != "" {
+				t.Errorf("ExecPatch.ApplyRepository() diff: %v", diff)
+			}
+			for _, want := range tt.wantLogs {
+				if !strings.Contains(logs.String(), want) {
+					t.Errorf("ExecPatch.ApplyRepository() log %q not found in:\n%s", want, logs.String())
+				}
+			}
+		})
+	}
+}
+
+func TestExecPatch_Validate(t *testing.T) {
+	allowed := []string{"/usr/local/bin/regenerate"}
+
+	if _, err := newExecPatch(map[string]any{"command": "/usr/local/bin/regenerate"}, allowed); err != nil {
+		t.Errorf("expected allowed command to be valid, got %v", err)
+	}
+
+	if _, err := newExecPatch(map[string]any{"command": "sh"}, allowed); err == nil {
+		t.Errorf("expected command that is not allowed to be rejected")
+	}
+
+	if _, err := newExecPatch(map[string]any{"command": "/usr/local/bin/regenerate"}, nil); err == nil {
+		t.Errorf("expected commands to be rejected without allowed commands")
+	}
+}
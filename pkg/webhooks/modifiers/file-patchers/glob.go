@@ -0,0 +1,147 @@
+package filepatchers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+
+	"github.com/metal-stack/metal-robot/pkg/config"
+	"github.com/metal-stack/metal-robot/pkg/utils"
+	"github.com/mitchellh/mapstructure"
+)
+
+// GlobPatch applies a patcher to every file of the repository that matches the glob pattern configured as file of the patcher.
+// Besides the wildcards of path.Match, the pattern may contain ** to match any number of directories.
+type GlobPatch struct {
+	patcher    Patcher
+	pattern    string
+	minMatches int
+}
+
+// withGlob wraps the given patcher in case its file is a glob pattern.
+func withGlob(p Patcher, rawConfig map[string]any) (Patcher, error) {
+	var typedConfig config.FileGlobConfig
+	err := mapstructure.Decode(rawConfig, &typedConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isGlob(typedConfig.File) {
+		return p, nil
+	}
+
+	// patchers that operate on the repository do not read the file through the content reader, so they cannot be applied per match
+	if _, ok := p.(RepositoryPatcher); ok {
+		return nil, fmt.Errorf("glob pattern %s is not supported by this modifier", typedConfig.File)
+	}
+
+	g := GlobPatch{
+		patcher:    p,
+		pattern:    typedConfig.File,
+		minMatches: 1,
+	}
+
+	if typedConfig.MinMatches != nil {
+		g.minMatches = *typedConfig.MinMatches
+	}
+
+	err = g.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+func (p GlobPatch) Apply(_ ContentReader, _ ContentWriter, _ string, _ utils.TemplateData) error {
+	return fmt.Errorf("patching glob pattern %s requires access to the repository", p.pattern)
+}
+
+// ApplyRepository applies the patcher to all matching files, the patcher reads and writes the matched file instead of the pattern
+func (p GlobPatch) ApplyRepository(ctx context.Context, _ *slog.Logger, repo Repository, newValue string, data utils.TemplateData) error {
+	files, err := repo.ListFiles(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	var matches []string
+	for _, file := range files {
+		if matchGlob(p.pattern, file) {
+			matches = append(matches, file)
+		}
+	}
+
+	if len(matches) < p.minMatches {
+		return fmt.Errorf("glob pattern %s matched %d files, expected at least %d", p.pattern, len(matches), p.minMatches)
+	}
+
+	for _, match := range matches {
+		resolve := func(file string) string {
+			if file == p.pattern {
+				return match
+			}
+			return file
+		}
+
+		cr := func(file string) ([]byte, error) {
+			return repo.ReadFile(ctx, resolve(file))
+		}
+		cw := func(file string, content []byte) error {
+			return repo.WriteFile(ctx, resolve(file), content)
+		}
+
+		err = p.patcher.Apply(cr, cw, newValue, data)
+		if err != nil {
+			return fmt.Errorf("error patching %s: %w", match, err)
+		}
+	}
+
+	return nil
+}
+
+func (p GlobPatch) Validate() error {
+	if _, err := path.Match(strings.ReplaceAll(p.pattern, "**", "*"), ""); err != nil {
+		return fmt.Errorf("invalid glob pattern %s: %w", p.pattern, err)
+	}
+	if p.minMatches < 0 {
+		return fmt.Errorf("min-matches must not be negative")
+	}
+	return p.patcher.Validate()
+}
+
+func isGlob(file string) bool {
+	return strings.ContainsAny(file, "*?[")
+}
+
+// matchGlob matches a slash separated path against the pattern, where a ** segment matches zero or more directories
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+
+		ok, err := path.Match(pattern[0], name[0])
+		if err != nil || !ok {
+			return false
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
@@ -0,0 +1,121 @@
+package filepatchers
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-robot/pkg/config"
+	"github.com/metal-stack/metal-robot/pkg/utils"
+)
+
+func TestGlobPatch_ApplyRepository(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    map[string]any
+		files   testRepository
+		want    testRepository
+		wantErr bool
+	}{
+		{
+			name: "patch all matching files",
+			args: map[string]any{"file": "deploy/*/values.yaml", "yaml-path": "image.tag", "mode": "node"},
+			files: testRepository{
+				"deploy/a/values.yaml":        "image:\n  tag: v0.1.0 # pinned\n",
+				"deploy/b/values.yaml":        "image:\n  tag: v0.2.0\n",
+				"deploy/b/nested/values.yaml": "image:\n  tag: v0.1.0\n",
+			},
+			want: testRepository{
+				"deploy/a/values.yaml":        "image:\n  tag: v0.3.0 # pinned\n",
+				"deploy/b/values.yaml":        "image:\n  tag: v0.3.0\n",
+				"deploy/b/nested/values.yaml": "image:\n  tag: v0.1.0\n",
+			},
+		},
+		{
+			name: "double star matches any directory depth",
+			args: map[string]any{"file": "docs/**/*.md", "line": 1},
+			files: testRepository{
+				"docs/index.md":       "v0.1.0\n",
+				"docs/guide/setup.md": "v0.1.0\n",
+				"README.md":           "v0.1.0\n",
+			},
+			want: testRepository{
+				"docs/index.md":       "v0.3.0\n",
+				"docs/guide/setup.md": "v0.3.0\n",
+				"README.md":           "v0.1.0\n",
+			},
+		},
+		{
+			name: "fewer matches than required",
+			args: map[string]any{"file": "deploy/*/value.yaml", "yaml-path": "image.tag"},
+			files: testRepository{
+				"deploy/a/values.yaml": "image:\n  tag: v0.1.0\n",
+			},
+			want: testRepository{
+				"deploy/a/values.yaml": "image:\n  tag: v0.1.0\n",
+			},
+			wantErr: true,
+		},
+		{
+			name: "no matches allowed",
+			args: map[string]any{"file": "deploy/*/value.yaml", "yaml-path": "image.tag", "min-matches": 0},
+			files: testRepository{
+				"deploy/a/values.yaml": "image:\n  tag: v0.1.0\n",
+			},
+			want: testRepository{
+				"deploy/a/values.yaml": "image:\n  tag: v0.1.0\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modifierType := YAMLPathVersionModifierName
+			if _, ok := tt.args["line"]; ok {
+				modifierType = LinePatchModifierName
+			}
+
+			p, err := InitPatcher(config.Modifier{Type: modifierType, Args: tt.args}, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := Apply(context.Background(), slog.New(slog.DiscardHandler), p, tt.files, "v0.3.0", utils.NewTemplateData("v0.3.0")); (err != nil) != tt.wantErr {
+				t.Errorf("GlobPatch.ApplyRepository() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, tt.files); diff != "" {
+				t.Errorf("GlobPatch.ApplyRepository() diff: %v", diff)
+			}
+		})
+	}
+}
+
+func TestInitPatcher_GlobForRepositoryPatcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		modifier config.Modifier
+		wantErr  bool
+	}{
+		{
+			name:     "actions uses patch",
+			modifier: config.Modifier{Type: ActionsUsesPatchModifierName, Args: map[string]any{"repository": "metal-stack/action-docker-make", "file": ".github/workflows/*.yaml"}},
+			wantErr:  true,
+		},
+		{
+			name:     "submodule patch",
+			modifier: config.Modifier{Type: SubmodulePatchModifierName, Args: map[string]any{"path": "metal-api", "file": "**"}},
+			wantErr:  true,
+		},
+		{
+			name:     "submodule patch without file",
+			modifier: config.Modifier{Type: SubmodulePatchModifierName, Args: map[string]any{"path": "metal-api"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := InitPatcher(tt.modifier, Options{}); (err != nil) != tt.wantErr {
+				t.Errorf("InitPatcher() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
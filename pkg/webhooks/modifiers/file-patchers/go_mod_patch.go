@@ -0,0 +1,197 @@
+package filepatchers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/metal-stack/metal-robot/pkg/config"
+	"github.com/metal-stack/metal-robot/pkg/utils"
+	"github.com/mitchellh/mapstructure"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+const (
+	defaultGoModFile = "go.mod"
+)
+
+type GoModPatch struct {
+	file           string
+	module         string
+	versionCompare bool
+	policy         *utils.VersionPolicy
+}
+
+func newGoModPatch(rawConfig map[string]any) (*GoModPatch, error) {
+	var typedConfig config.GoModPatchConfig
+	err := mapstructure.Decode(rawConfig, &typedConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	p := GoModPatch{
+		file:           defaultGoModFile,
+		module:         typedConfig.Module,
+		versionCompare: true,
+	}
+
+	if typedConfig.File != nil {
+		p.file = *typedConfig.File
+	}
+
+	if typedConfig.VersionCompare != nil {
+		p.versionCompare = *typedConfig.VersionCompare
+	}
+
+	p.policy, err = utils.NewVersionPolicy(typedConfig.VersionPolicy, utils.PrereleaseAllow)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// Apply updates the require directive of the module to the given version. Replace directives that pin the module
+// to a version of itself are updated as well. The go.sum is not touched.
+func (p GoModPatch) Apply(cr ContentReader, cw ContentWriter, newValue string, _ utils.TemplateData) error {
+	content, err := cr(p.file)
+	if err != nil {
+		return fmt.Errorf("error reading patch file %w", err)
+	}
+
+	version := newValue
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+
+	if !semver.IsValid(version) {
+		return fmt.Errorf("%q is not a valid module version", newValue)
+	}
+
+	f, err := modfile.Parse(p.file, content, nil)
+	if err != nil {
+		return fmt.Errorf("error parsing %s: %w", p.file, err)
+	}
+
+	path := modulePath(p.module, version)
+
+	var current *modfile.Require
+	for _, r := range f.Require {
+		if r.Mod.Path == path {
+			current = r
+			break
+		}
+	}
+
+	if current == nil {
+		for _, r := range f.Require {
+			if prefix, _, _ := module.SplitPathVersion(r.Mod.Path); prefix == modulePrefix(p.module) {
+				return fmt.Errorf("%s requires %s, updating to %s requires changing the import paths", p.file, r.Mod.Path, path)
+			}
+		}
+
+		return fmt.Errorf("module %s is not required in %s", path, p.file)
+	}
+
+	// module versions are compared with the module semantics first, which orders pseudo and incompatible versions correctly
+	if p.versionCompare && semver.Compare(version, current.Mod.Version) <= 0 {
+		return nil
+	}
+
+	allowed, err := allowsUpdate(current.Mod.Version, version, false, p.versionCompare, p.policy)
+	if err != nil {
+		return err
+	}
+
+	if !allowed {
+		return nil
+	}
+
+	err = f.AddRequire(path, version)
+	if err != nil {
+		return fmt.Errorf("error updating require directive: %w", err)
+	}
+
+	for _, r := range f.Replace {
+		// only replacements that pin the module to a version of itself are updated, replacements with forks,
+		// local directories or for specific versions are left as they are
+		if r.Old.Path != path || r.Old.Version != "" || r.New.Path != path || r.New.Version == "" {
+			continue
+		}
+
+		r.New.Version = version
+		updateReplaceLine(r)
+	}
+
+	f.Cleanup()
+
+	content, err = f.Format()
+	if err != nil {
+		return fmt.Errorf("error formatting %s: %w", p.file, err)
+	}
+
+	err = cw(p.file, content)
+	if err != nil {
+		return fmt.Errorf("error writing patch file %w", err)
+	}
+
+	return nil
+}
+
+// modulePath returns the module path for the major version of the given version, e.g. github.com/metal-stack/metal-go/v2
+func modulePath(path, version string) string {
+	prefix := modulePrefix(path)
+
+	major := semver.Major(version)
+
+	if strings.HasPrefix(prefix, "gopkg.in/") {
+		return prefix + "." + major
+	}
+
+	if major == "v0" || major == "v1" {
+		return prefix
+	}
+
+	return prefix + "/" + major
+}
+
+func modulePrefix(path string) string {
+	prefix, _, ok := module.SplitPathVersion(path)
+	if !ok {
+		return path
+	}
+	return prefix
+}
+
+func updateReplaceLine(r *modfile.Replace) {
+	tokens := []string{"replace", modfile.AutoQuote(r.Old.Path)}
+	if r.Old.Version != "" {
+		tokens = append(tokens, r.Old.Version)
+	}
+	tokens = append(tokens, "=>", modfile.AutoQuote(r.New.Path))
+	if r.New.Version != "" {
+		tokens = append(tokens, r.New.Version)
+	}
+
+	if r.Syntax.InBlock {
+		tokens = tokens[1:]
+	}
+
+	r.Syntax.Token = tokens
+}
+
+func (p GoModPatch) Validate() error {
+	if p.file == "" {
+		return fmt.Errorf("file must be specified")
+	}
+	if p.module == "" {
+		return fmt.Errorf("module must be specified")
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package filepatchers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-robot/pkg/config"
+	"github.com/metal-stack/metal-robot/pkg/utils"
+)
+
+func TestGoModPatch_Apply(t *testing.T) {
+	input := `module github.com/metal-stack/metal-robot
+
+go 1.26
+
+require (
+	github.com/metal-stack/metal-go v0.40.1
+	github.com/metal-stack/metal-lib v0.23.4 // pinned until metal-go is updated
+	github.com/metal-stack/v v1.0.0
+)
+
+require github.com/metal-stack/security v0.9.2 // indirect
+
+replace github.com/metal-stack/metal-lib => github.com/metal-stack/metal-lib v0.23.4
+
+replace (
+	github.com/metal-stack/security v0.9.2 => github.com/metal-stack/security v0.9.1
+	github.com/metal-stack/metal-go => ../metal-go
+)
+`
+	tests := []struct {
+		name     string
+		p        GoModPatch
+		newValue string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "update require and keep comments",
+			p:        GoModPatch{file: "go.mod", module: "github.com/metal-stack/metal-go", versionCompare: true},
+			newValue: "v0.41.0",
+			want:     strings.Replace(input, "metal-go v0.40.1", "metal-go v0.41.0", 1),
+		},
+		{
+			name:     "update require and replace directive",
+			p:        GoModPatch{file: "go.mod", module: "github.com/metal-stack/metal-lib", versionCompare: true},
+			newValue: "v0.24.0",
+			want:     strings.ReplaceAll(input, "metal-lib v0.23.4", "metal-lib v0.24.0"),
+		},
+		{
+			name:     "update indirect require and keep versioned replace directive",
+			p:        GoModPatch{file: "go.mod", module: "github.com/metal-stack/security", versionCompare: true},
+			newValue: "0.9.3",
+			want:     strings.Replace(input, "require github.com/metal-stack/security v0.9.2 // indirect", "require github.com/metal-stack/security v0.9.3 // indirect", 1),
+		},
+		{
+			name:     "change nothing on lower version",
+			p:        GoModPatch{file: "go.mod", module: "github.com/metal-stack/metal-go", versionCompare: true},
+			newValue: "v0.40.0",
+			want:     "",
+		},
+		{
+			name:     "change nothing on increment not allowed by the version policy",
+			p:        GoModPatch{file: "go.mod", module: "github.com/metal-stack/metal-go", versionCompare: true, policy: versionPolicy(t, config.VersionPolicyConfig{Increments: []string{utils.IncrementPatch}})},
+			newValue: "v0.41.0",
+			want:     "",
+		},
+		{
+			name:     "update patch release allowed by the version policy",
+			p:        GoModPatch{file: "go.mod", module: "github.com/metal-stack/metal-go", versionCompare: true, policy: versionPolicy(t, config.VersionPolicyConfig{Increments: []string{utils.IncrementPatch}})},
+			newValue: "v0.40.2",
+			want:     strings.Replace(input, "metal-go v0.40.1", "metal-go v0.40.2", 1),
+		},
+		{
+			name:     "change nothing on prerelease skipped by the version policy without version comparison",
+			p:        GoModPatch{file: "go.mod", module: "github.com/metal-stack/metal-go", policy: versionPolicy(t, config.VersionPolicyConfig{Prerelease: new(utils.PrereleaseSkip)})},
+			newValue: "v0.41.0-rc.1",
+			want:     "",
+		},
+		{
+			name:     "major version upgrade requires import path changes",
+			p:        GoModPatch{file: "go.mod", module: "github.com/metal-stack/metal-go", versionCompare: true},
+			newValue: "v2.0.0",
+			wantErr:  true,
+		},
+		{
+			name:     "module not required",
+			p:        GoModPatch{file: "go.mod", module: "github.com/metal-stack/metal-core", versionCompare: true},
+			newValue: "v0.1.0",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			cn := func(file string) ([]byte, error) {
+				return []byte(input), nil
+			}
+			cw := func(file string, content []byte) error {
+				got = string(content)
+				return nil
+			}
+			if err := tt.p.Apply(cn, cw, tt.newValue, utils.NewTemplateData(tt.newValue)); (err != nil) != tt.wantErr {
+				t.Errorf("GoModPatch.Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("GoModPatch.Apply() diff: %v", diff)
+			}
+		})
+	}
+}
+
+func Test_modulePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		version string
+		want    string
+	}{
+		{path: "github.com/metal-stack/metal-go", version: "v0.41.0", want: "github.com/metal-stack/metal-go"},
+		{path: "github.com/metal-stack/metal-go", version: "v1.2.0", want: "github.com/metal-stack/metal-go"},
+		{path: "github.com/metal-stack/metal-go", version: "v2.0.0", want: "github.com/metal-stack/metal-go/v2"},
+		{path: "github.com/metal-stack/metal-go/v2", version: "v3.1.0", want: "github.com/metal-stack/metal-go/v3"},
+		{path: "github.com/metal-stack/metal-go/v2", version: "v1.1.0", want: "github.com/metal-stack/metal-go"},
+		{path: "gopkg.in/yaml.v2", version: "v3.0.1", want: "gopkg.in/yaml.v3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path+"@"+tt.version, func(t *testing.T) {
+			if got := modulePath(tt.path, tt.version); got != tt.want {
+				t.Errorf("modulePath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
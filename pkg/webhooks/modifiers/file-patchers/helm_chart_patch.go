@@ -0,0 +1,231 @@
+package filepatchers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/metal-stack/metal-robot/pkg/config"
+	"github.com/metal-stack/metal-robot/pkg/utils"
+	"github.com/mitchellh/mapstructure"
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	defaultChartFile = "Chart.yaml"
+
+	HelmChartVersionIncrementMajor string = "major"
+	HelmChartVersionIncrementMinor string = "minor"
+	HelmChartVersionIncrementPatch string = "patch"
+)
+
+type HelmChartPatch struct {
+	file             string
+	appVersion       bool
+	dependency       *string
+	versionIncrement *string
+	template         *string
+	versionCompare   bool
+	policy           *utils.VersionPolicy
+}
+
+type chartMetadata struct {
+	Version      string `yaml:"version"`
+	AppVersion   string `yaml:"appVersion"`
+	Dependencies []struct {
+		Name    string `yaml:"name"`
+		Version string `yaml:"version"`
+	} `yaml:"dependencies"`
+}
+
+func newHelmChartPatch(rawConfig map[string]any) (*HelmChartPatch, error) {
+	var typedConfig config.HelmChartPatchConfig
+	err := mapstructure.Decode(rawConfig, &typedConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	p := HelmChartPatch{
+		file:             defaultChartFile,
+		appVersion:       true,
+		dependency:       typedConfig.Dependency,
+		versionIncrement: typedConfig.VersionIncrement,
+		template:         typedConfig.Template,
+		versionCompare:   true,
+	}
+
+	if typedConfig.File != nil {
+		p.file = *typedConfig.File
+	}
+
+	if typedConfig.AppVersion != nil {
+		p.appVersion = *typedConfig.AppVersion
+	}
+
+	if typedConfig.VersionCompare != nil {
+		p.versionCompare = *typedConfig.VersionCompare
+	}
+
+	p.policy, err = utils.NewVersionPolicy(typedConfig.VersionPolicy, utils.PrereleaseAllow)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// Apply sets the app version and the version of the configured dependency to the new value. If anything changed,
+// the chart version is incremented as configured. Comments and formatting of the chart file are preserved.
+// Charts without an appVersion, e.g. charts that only bundle dependencies, keep having no appVersion.
+func (p HelmChartPatch) Apply(cr ContentReader, cw ContentWriter, newValue string, data utils.TemplateData) error {
+	content, err := cr(p.file)
+	if err != nil {
+		return fmt.Errorf("error reading patch file %w", err)
+	}
+
+	var chart chartMetadata
+	err = yaml.Unmarshal(content, &chart)
+	if err != nil {
+		return fmt.Errorf("error parsing %s: %w", p.file, err)
+	}
+
+	patched := false
+
+	if p.appVersion && chart.AppVersion != "" {
+		ok, err := p.isNewer(chart.AppVersion, newValue)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			value, err := p.value(newValue, chart.AppVersion, data)
+			if err != nil {
+				return err
+			}
+
+			content, err = setYAMLNode(content, "appVersion", value)
+			if err != nil {
+				return fmt.Errorf("error patching app version: %w", err)
+			}
+			patched = true
+		}
+	}
+
+	if p.dependency != nil {
+		idx := -1
+		for i, dep := range chart.Dependencies {
+			if dep.Name == *p.dependency {
+				idx = i
+				break
+			}
+		}
+
+		if idx < 0 {
+			return fmt.Errorf("dependency %s not found in %s", *p.dependency, p.file)
+		}
+
+		ok, err := p.isNewer(chart.Dependencies[idx].Version, newValue)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			value, err := p.value(newValue, chart.Dependencies[idx].Version, data)
+			if err != nil {
+				return err
+			}
+
+			content, err = setYAMLNode(content, "dependencies."+strconv.Itoa(idx)+".version", value)
+			if err != nil {
+				return fmt.Errorf("error patching dependency version: %w", err)
+			}
+			patched = true
+		}
+	}
+
+	if !patched {
+		return nil
+	}
+
+	if p.versionIncrement != nil {
+		version, err := incrementChartVersion(chart.Version, *p.versionIncrement)
+		if err != nil {
+			return err
+		}
+
+		content, err = setYAMLNode(content, "version", version)
+		if err != nil {
+			return fmt.Errorf("error patching chart version: %w", err)
+		}
+	}
+
+	err = cw(p.file, content)
+	if err != nil {
+		return fmt.Errorf("error writing patch file %w", err)
+	}
+
+	return nil
+}
+
+func (p HelmChartPatch) value(newValue, old string, data utils.TemplateData) (string, error) {
+	if p.template == nil {
+		return newValue, nil
+	}
+	return renderTemplate(*p.template, newValue, old, data)
+}
+
+func (p HelmChartPatch) isNewer(old, newValue string) (bool, error) {
+	// dependency versions are often constraints like ~1.2.0, so the version is always extracted
+	return allowsUpdate(old, newValue, true, p.versionCompare, p.policy)
+}
+
+func incrementChartVersion(current, increment string) (string, error) {
+	version, err := semver.NewVersion(strings.TrimPrefix(current, "v"))
+	if err != nil {
+		return "", fmt.Errorf("chart version %q is not a semantic version: %w", current, err)
+	}
+
+	var next semver.Version
+	switch increment {
+	case HelmChartVersionIncrementMajor:
+		next = version.IncMajor()
+	case HelmChartVersionIncrementMinor:
+		next = version.IncMinor()
+	case HelmChartVersionIncrementPatch:
+		next = version.IncPatch()
+	default:
+		return "", fmt.Errorf("unsupported version increment: %s", increment)
+	}
+
+	if strings.HasPrefix(current, "v") {
+		return "v" + next.String(), nil
+	}
+
+	return next.String(), nil
+}
+
+func (p HelmChartPatch) Validate() error {
+	if p.file == "" {
+		return fmt.Errorf("file must be specified")
+	}
+	if !p.appVersion && p.dependency == nil {
+		return fmt.Errorf("either app-version or dependency must be patched")
+	}
+	if p.versionIncrement != nil {
+		switch *p.versionIncrement {
+		case HelmChartVersionIncrementMajor, HelmChartVersionIncrementMinor, HelmChartVersionIncrementPatch:
+		default:
+			return fmt.Errorf("version-increment must be one of %s, %s or %s", HelmChartVersionIncrementMajor, HelmChartVersionIncrementMinor, HelmChartVersionIncrementPatch)
+		}
+	}
+	if err := validateTemplate("template", p.template); err != nil {
+		return err
+	}
+	return nil
+}
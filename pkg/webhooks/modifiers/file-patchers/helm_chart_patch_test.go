@@ -0,0 +1,113 @@
+package filepatchers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-robot/pkg/utils"
+)
+
+func TestHelmChartPatch_Apply(t *testing.T) {
+	input := `apiVersion: v2
+name: metal-control-plane
+description: A Helm chart for the metal-stack control plane
+# the chart version is bumped by the metal-robot
+version: 0.4.2
+appVersion: "v0.37.2"
+dependencies:
+  - name: nsq
+    version: ~1.2.0
+    repository: https://nsqio.github.io/helm-chart
+  - name: metal-db
+    version: "0.3.1"
+    repository: oci://ghcr.io/metal-stack/charts
+`
+	tpl := "~%s"
+
+	withoutAppVersion := strings.Replace(input, "appVersion: \"v0.37.2\"\n", "", 1)
+
+	tests := []struct {
+		name     string
+		p        HelmChartPatch
+		input    *string
+		newValue string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "bump app version and chart version",
+			p:        HelmChartPatch{file: "Chart.yaml", appVersion: true, versionIncrement: new(HelmChartVersionIncrementMinor), versionCompare: true},
+			newValue: "v0.38.0",
+			want: strings.NewReplacer(
+				`appVersion: "v0.37.2"`, `appVersion: "v0.38.0"`,
+				"version: 0.4.2", "version: 0.5.0",
+			).Replace(input),
+		},
+		{
+			name:     "bump dependency without app version",
+			p:        HelmChartPatch{file: "Chart.yaml", dependency: new("metal-db"), versionIncrement: new(HelmChartVersionIncrementPatch), versionCompare: true},
+			newValue: "0.4.0",
+			want: strings.NewReplacer(
+				`version: "0.3.1"`, `version: "0.4.0"`,
+				"version: 0.4.2", "version: 0.4.3",
+			).Replace(input),
+		},
+		{
+			name:     "bump dependency constraint with template",
+			p:        HelmChartPatch{file: "Chart.yaml", dependency: new("nsq"), template: &tpl, versionCompare: true},
+			newValue: "1.3.0",
+			want:     strings.Replace(input, "version: ~1.2.0", "version: ~1.3.0", 1),
+		},
+		{
+			name:     "bump dependency of chart without app version",
+			p:        HelmChartPatch{file: "Chart.yaml", appVersion: true, dependency: new("metal-db"), versionIncrement: new(HelmChartVersionIncrementPatch), versionCompare: true},
+			input:    &withoutAppVersion,
+			newValue: "0.4.0",
+			want: strings.NewReplacer(
+				`version: "0.3.1"`, `version: "0.4.0"`,
+				"version: 0.4.2", "version: 0.4.3",
+			).Replace(withoutAppVersion),
+		},
+		{
+			name:     "change nothing on chart without app version",
+			p:        HelmChartPatch{file: "Chart.yaml", appVersion: true, versionIncrement: new(HelmChartVersionIncrementMinor), versionCompare: true},
+			input:    &withoutAppVersion,
+			newValue: "v0.38.0",
+			want:     "",
+		},
+		{
+			name:     "change nothing on lower version",
+			p:        HelmChartPatch{file: "Chart.yaml", appVersion: true, versionIncrement: new(HelmChartVersionIncrementMinor), versionCompare: true},
+			newValue: "v0.37.1",
+			want:     "",
+		},
+		{
+			name:     "dependency not found",
+			p:        HelmChartPatch{file: "Chart.yaml", dependency: new("metal-api"), versionCompare: true},
+			newValue: "v0.38.0",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			cn := func(file string) ([]byte, error) {
+				if tt.input != nil {
+					return []byte(*tt.input), nil
+				}
+				return []byte(input), nil
+			}
+			cw := func(file string, content []byte) error {
+				got = string(content)
+				return nil
+			}
+			if err := tt.p.Apply(cn, cw, tt.newValue, utils.NewTemplateData(tt.newValue)); (err != nil) != tt.wantErr {
+				t.Errorf("HelmChartPatch.Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("HelmChartPatch.Apply() diff: %v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package filepatchers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"testing"
+
+	"github.com/metal-stack/metal-robot/pkg/config"
+	"github.com/metal-stack/metal-robot/pkg/utils"
+)
+
+func versionPolicy(t *testing.T, c config.VersionPolicyConfig) *utils.VersionPolicy {
+	policy, err := utils.NewVersionPolicy(&c, utils.PrereleaseAllow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return policy
+}
+
+type testRepository map[string]string
+
+func (r testRepository) ReadFile(_ context.Context, path string) ([]byte, error) {
+	content, ok := r[path]
+	if !ok {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+	return []byte(content), nil
+}
+
+func (r testRepository) WriteFile(_ context.Context, path string, data []byte) error {
+	r[path] = string(data)
+	return nil
+}
+
+func (r testRepository) ListFiles(_ context.Context, _ string) ([]string, error) {
+	var files []string
+	for f := range r {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// applyDiff applies the patcher to the given files and returns the unified diff of the changes.
+func applyDiff(t *testing.T, p Patcher, repo Repository, newValue string) string {
+	t.Helper()
+
+	rec := NewRecorder(repo)
+
+	err := Apply(context.Background(), slog.New(slog.DiscardHandler), p, rec, newValue, utils.NewTemplateData(newValue))
+	if err != nil {
+		t.Fatalf("error applying patcher: %v", err)
+	}
+
+	diff, err := rec.Diff()
+	if err != nil {
+		t.Fatalf("error rendering diff: %v", err)
+	}
+
+	return diff
+}
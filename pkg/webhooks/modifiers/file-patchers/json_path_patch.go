@@ -0,0 +1,109 @@
+package filepatchers
+
+import (
+	"fmt"
+
+	"github.com/metal-stack/metal-robot/pkg/config"
+	"github.com/metal-stack/metal-robot/pkg/utils"
+	"github.com/mitchellh/mapstructure"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+type JSONPathPatch struct {
+	file           string
+	jsonPath       string
+	template       *string
+	versionCompare bool
+	policy         *utils.VersionPolicy
+}
+
+func newJSONPathPatch(rawConfig map[string]any) (*JSONPathPatch, error) {
+	var typedConfig config.JSONPathPatchConfig
+	err := mapstructure.Decode(rawConfig, &typedConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	p := JSONPathPatch{
+		file:           typedConfig.File,
+		jsonPath:       typedConfig.JSONPath,
+		template:       typedConfig.Template,
+		versionCompare: true,
+	}
+
+	if typedConfig.VersionCompare != nil {
+		p.versionCompare = *typedConfig.VersionCompare
+	}
+
+	p.policy, err = utils.NewVersionPolicy(typedConfig.VersionPolicy, utils.PrereleaseAllow)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// Apply replaces the value at the json path in place, the formatting of the rest of the file is preserved
+func (p JSONPathPatch) Apply(cr ContentReader, cw ContentWriter, newValue string, data utils.TemplateData) error {
+	content, err := cr(p.file)
+	if err != nil {
+		return fmt.Errorf("error reading patch file %w", err)
+	}
+
+	if !gjson.ValidBytes(content) {
+		return fmt.Errorf("file %s does not contain valid json", p.file)
+	}
+
+	old := gjson.GetBytes(content, p.jsonPath)
+
+	if p.versionCompare && !old.Exists() {
+		return fmt.Errorf("path not found in json: %v", p.jsonPath)
+	}
+
+	allowed, err := allowsUpdate(old.String(), newValue, p.template != nil, p.versionCompare, p.policy)
+	if err != nil {
+		return err
+	}
+
+	if !allowed {
+		return nil
+	}
+
+	if p.template != nil {
+		newValue, err = renderTemplate(*p.template, newValue, old.String(), data)
+		if err != nil {
+			return err
+		}
+	}
+
+	content, err = sjson.SetBytes(content, p.jsonPath, newValue)
+	if err != nil {
+		return err
+	}
+
+	err = cw(p.file, content)
+	if err != nil {
+		return fmt.Errorf("error writing patch file %w", err)
+	}
+
+	return nil
+}
+
+func (p JSONPathPatch) Validate() error {
+	if p.file == "" {
+		return fmt.Errorf("file must be specified")
+	}
+	if p.jsonPath == "" {
+		return fmt.Errorf("json-path must be specified")
+	}
+	if err := validateTemplate("template", p.template); err != nil {
+		return err
+	}
+	return nil
+}
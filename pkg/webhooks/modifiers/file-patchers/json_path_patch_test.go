@@ -0,0 +1,102 @@
+package filepatchers
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-robot/pkg/utils"
+)
+
+func TestJSONPathPatch_Apply(t *testing.T) {
+	input := `{
+    "name": "metal-ui",
+    "version": "0.3.1",
+    "dependencies": {
+        "@metal-stack/api":   "^0.12.0",
+        "react": "18.2.0"
+    }
+}
+`
+	tpl := "^%s"
+
+	tests := []struct {
+		name     string
+		p        JSONPathPatch
+		newValue string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "replace a path and keep formatting",
+			p:        JSONPathPatch{file: "package.json", jsonPath: "version", versionCompare: true},
+			newValue: "v0.4.0",
+			want: `{
+    "name": "metal-ui",
+    "version": "v0.4.0",
+    "dependencies": {
+        "@metal-stack/api":   "^0.12.0",
+        "react": "18.2.0"
+    }
+}
+`,
+		},
+		{
+			name:     "replace with a template and version comparison",
+			p:        JSONPathPatch{file: "package.json", jsonPath: "dependencies.@metal-stack/api", template: &tpl, versionCompare: true},
+			newValue: "0.13.0",
+			want: `{
+    "name": "metal-ui",
+    "version": "0.3.1",
+    "dependencies": {
+        "@metal-stack/api":   "^0.13.0",
+        "react": "18.2.0"
+    }
+}
+`,
+		},
+		{
+			name:     "replace with a go template",
+			p:        JSONPathPatch{file: "package.json", jsonPath: "dependencies.@metal-stack/api", template: new("~{{ .Major }}.{{ .Minor }}"), versionCompare: true},
+			newValue: "v0.13.2",
+			want: `{
+    "name": "metal-ui",
+    "version": "0.3.1",
+    "dependencies": {
+        "@metal-stack/api":   "~0.13",
+        "react": "18.2.0"
+    }
+}
+`,
+		},
+		{
+			name:     "change nothing on lower version",
+			p:        JSONPathPatch{file: "package.json", jsonPath: "dependencies.react", versionCompare: true},
+			newValue: "18.1.0",
+			want:     "",
+		},
+		{
+			name:     "path not found with version comparison",
+			p:        JSONPathPatch{file: "package.json", jsonPath: "devDependencies.react", versionCompare: true},
+			newValue: "18.1.0",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			cn := func(file string) ([]byte, error) {
+				return []byte(input), nil
+			}
+			cw := func(file string, content []byte) error {
+				got = string(content)
+				return nil
+			}
+			if err := tt.p.Apply(cn, cw, tt.newValue, utils.NewTemplateData(tt.newValue)); (err != nil) != tt.wantErr {
+				t.Errorf("JSONPathPatch.Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("JSONPathPatch.Apply() diff: %v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package filepatchers
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/metal-stack/metal-robot/pkg/config"
+	"github.com/metal-stack/metal-robot/pkg/utils"
+	"github.com/mitchellh/mapstructure"
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	defaultKustomizationFile = "kustomization.yaml"
+)
+
+type KustomizeImagePatch struct {
+	file           string
+	image          string
+	newName        *string
+	clearDigest    bool
+	template       *string
+	versionCompare bool
+	policy         *utils.VersionPolicy
+}
+
+func newKustomizeImagePatch(rawConfig map[string]any) (*KustomizeImagePatch, error) {
+	var typedConfig config.KustomizeImagePatchConfig
+	err := mapstructure.Decode(rawConfig, &typedConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	p := KustomizeImagePatch{
+		file:           defaultKustomizationFile,
+		image:          typedConfig.Image,
+		newName:        typedConfig.NewName,
+		template:       typedConfig.Template,
+		versionCompare: true,
+	}
+
+	if typedConfig.File != nil {
+		p.file = *typedConfig.File
+	}
+
+	if typedConfig.ClearDigest != nil {
+		p.clearDigest = *typedConfig.ClearDigest
+	}
+
+	if typedConfig.VersionCompare != nil {
+		p.versionCompare = *typedConfig.VersionCompare
+	}
+
+	p.policy, err = utils.NewVersionPolicy(typedConfig.VersionPolicy, utils.PrereleaseAllow)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// Apply sets the newTag of the images entry with the configured name. If only the tag changes, the rest of the file
+// is preserved as it is. When entries or keys need to be added or removed, the file is re-encoded, which keeps
+// comments but normalizes the indentation.
+func (p KustomizeImagePatch) Apply(cr ContentReader, cw ContentWriter, newValue string, data utils.TemplateData) error {
+	content, err := cr(p.file)
+	if err != nil {
+		return fmt.Errorf("error reading patch file %w", err)
+	}
+
+	var root yaml.Node
+	err = yaml.Unmarshal(content, &root)
+	if err != nil {
+		return fmt.Errorf("error parsing %s: %w", p.file, err)
+	}
+
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("%s does not contain a kustomization", p.file)
+	}
+
+	kustomization := root.Content[0]
+
+	images := mappingValue(kustomization, "images")
+	if images == nil {
+		images = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		kustomization.Content = append(kustomization.Content, scalarNode("images"), images)
+	}
+
+	if images.Kind != yaml.SequenceNode {
+		return fmt.Errorf("images in %s is not a list", p.file)
+	}
+
+	var entry *yaml.Node
+	for _, e := range images.Content {
+		if name := mappingValue(e, "name"); name != nil && name.Value == p.image {
+			entry = e
+			break
+		}
+	}
+
+	if entry == nil {
+		entry = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{scalarNode("name"), scalarNode(p.image)}}
+		if p.newName != nil {
+			entry.Content = append(entry.Content, scalarNode("newName"), scalarNode(*p.newName))
+		}
+		images.Content = append(images.Content, entry)
+	}
+
+	tag := mappingValue(entry, "newTag")
+
+	// without a current tag there is no version to compare with, but the new version still needs to be allowed
+	old := ""
+	if tag != nil {
+		old = tag.Value
+	}
+
+	allowed, err := allowsUpdate(old, newValue, p.template != nil && tag != nil, p.versionCompare, p.policy)
+	if err != nil {
+		return err
+	}
+
+	if !allowed {
+		return nil
+	}
+
+	if p.template != nil {
+		newValue, err = renderTemplate(*p.template, newValue, old, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	digest := mappingValue(entry, "digest")
+
+	if tag != nil && tag.Line > 0 && tag.Kind == yaml.ScalarNode && (digest == nil || !p.clearDigest) {
+		content, err = replaceScalar(content, tag, newValue)
+		if err != nil {
+			return err
+		}
+
+		return p.write(cw, content)
+	}
+
+	if tag == nil {
+		entry.Content = append(entry.Content, scalarNode("newTag"), scalarNode(newValue))
+	} else {
+		tag.Kind = yaml.ScalarNode
+		tag.Tag = "!!str"
+		tag.Value = newValue
+	}
+
+	if p.clearDigest {
+		removeMappingKey(entry, "digest")
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+
+	err = enc.Encode(&root)
+	if err != nil {
+		return fmt.Errorf("error encoding %s: %w", p.file, err)
+	}
+
+	err = enc.Close()
+	if err != nil {
+		return fmt.Errorf("error encoding %s: %w", p.file, err)
+	}
+
+	return p.write(cw, buf.Bytes())
+}
+
+func (p KustomizeImagePatch) write(cw ContentWriter, content []byte) error {
+	err := cw(p.file, content)
+	if err != nil {
+		return fmt.Errorf("error writing patch file %w", err)
+	}
+
+	return nil
+}
+
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+func removeMappingKey(n *yaml.Node, key string) {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			n.Content = append(n.Content[:i], n.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+func (p KustomizeImagePatch) Validate() error {
+	if p.file == "" {
+		return fmt.Errorf("file must be specified")
+	}
+	if p.image == "" {
+		return fmt.Errorf("image must be specified")
+	}
+	if err := validateTemplate("template", p.template); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package filepatchers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-robot/pkg/config"
+	"github.com/metal-stack/metal-robot/pkg/utils"
+)
+
+func TestKustomizeImagePatch_Apply(t *testing.T) {
+	input := `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+    - deployment.yaml
+# images are updated by the metal-robot
+images:
+    - name: ghcr.io/metal-stack/metal-api
+      newTag: v0.37.2 # current release
+    - name: metal-console
+      newName: ghcr.io/metal-stack/metal-console
+      newTag: v0.7.1
+      digest: sha256:4d1a6b2c3e7f8a9b0c1d2e3f4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3b
+`
+	tests := []struct {
+		name     string
+		p        KustomizeImagePatch
+		input    string
+		newValue string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "update tag in place",
+			p:        KustomizeImagePatch{file: "kustomization.yaml", image: "ghcr.io/metal-stack/metal-api", versionCompare: true},
+			input:    input,
+			newValue: "v0.38.0",
+			want:     strings.Replace(input, "newTag: v0.37.2 # current", "newTag: v0.38.0 # current", 1),
+		},
+		{
+			name:     "update tag and clear digest",
+			p:        KustomizeImagePatch{file: "kustomization.yaml", image: "metal-console", clearDigest: true, versionCompare: true},
+			input:    input,
+			newValue: "v0.8.0",
+			want: `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+  - deployment.yaml
+# images are updated by the metal-robot
+images:
+  - name: ghcr.io/metal-stack/metal-api
+    newTag: v0.37.2 # current release
+  - name: metal-console
+    newName: ghcr.io/metal-stack/metal-console
+    newTag: v0.8.0
+`,
+		},
+		{
+			name:     "add missing entry",
+			p:        KustomizeImagePatch{file: "kustomization.yaml", image: "metal-core", newName: new("ghcr.io/metal-stack/metal-core"), versionCompare: true},
+			input:    "resources:\n  - deployment.yaml\n",
+			newValue: "v0.12.0",
+			want: `resources:
+  - deployment.yaml
+images:
+  - name: metal-core
+    newName: ghcr.io/metal-stack/metal-core
+    newTag: v0.12.0
+`,
+		},
+		{
+			name:     "do not add missing entry for a prerelease with version policy",
+			p:        KustomizeImagePatch{file: "kustomization.yaml", image: "metal-core", versionCompare: true, policy: versionPolicy(t, config.VersionPolicyConfig{Prerelease: new(utils.PrereleaseSkip)})},
+			input:    "resources:\n  - deployment.yaml\n",
+			newValue: "v0.12.0-rc.1",
+			want:     "",
+		},
+		{
+			name:     "change nothing on lower version",
+			p:        KustomizeImagePatch{file: "kustomization.yaml", image: "ghcr.io/metal-stack/metal-api", versionCompare: true},
+			input:    input,
+			newValue: "v0.37.0",
+			want:     "",
+		},
+		{
+			name:     "images is not a list",
+			p:        KustomizeImagePatch{file: "kustomization.yaml", image: "ghcr.io/metal-stack/metal-api", versionCompare: true},
+			input:    "images: {}\n",
+			newValue: "v0.38.0",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			cn := func(file string) ([]byte, error) {
+				return []byte(tt.input), nil
+			}
+			cw := func(file string, content []byte) error {
+				got = string(content)
+				return nil
+			}
+			if err := tt.p.Apply(cn, cw, tt.newValue, utils.NewTemplateData(tt.newValue)); (err != nil) != tt.wantErr {
+				t.Errorf("KustomizeImagePatch.Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("KustomizeImagePatch.Apply() diff: %v", diff)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/metal-stack/metal-robot/pkg/config"
+	"github.com/metal-stack/metal-robot/pkg/utils"
 	"github.com/mitchellh/mapstructure"
 )
 
@@ -35,7 +36,7 @@ func newLinePatch(rawConfig map[string]any) (*LinePatch, error) {
 	return &p, nil
 }
 
-func (p LinePatch) Apply(cr ContentReader, cw ContentWriter, newValue string) error {
+func (p LinePatch) Apply(cr ContentReader, cw ContentWriter, newValue string, data utils.TemplateData) error {
 	content, err := cr(p.file)
 	if err != nil {
 		return fmt.Errorf("error reading patch file %w", err)
@@ -49,7 +50,10 @@ func (p LinePatch) Apply(cr ContentReader, cw ContentWriter, newValue string) er
 	if p.replaceTemplate == nil {
 		lines[p.line-1] = newValue
 	} else {
-		lines[p.line-1] = fmt.Sprintf(*p.replaceTemplate, newValue)
+		lines[p.line-1], err = renderTemplate(*p.replaceTemplate, newValue, lines[p.line-1], data)
+		if err != nil {
+			return err
+		}
 	}
 
 	new := strings.Join(lines, "\n")
@@ -69,5 +73,8 @@ func (p LinePatch) Validate() error {
 	if p.line <= 0 {
 		return fmt.Errorf("line cannot be 0 or lower, starts at 1")
 	}
+	if err := validateTemplate("template", p.replaceTemplate); err != nil {
+		return err
+	}
 	return nil
 }
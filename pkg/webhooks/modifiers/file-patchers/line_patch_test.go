@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-robot/pkg/utils"
 )
 
 func TestLinePatch_Apply(t *testing.T) {
@@ -42,7 +43,7 @@ a
 				}
 				return nil
 			}
-			if err := tt.r.Apply(cn, cw, tt.value); (err != nil) != tt.wantErr {
+			if err := tt.r.Apply(cn, cw, tt.value, utils.NewTemplateData(tt.value)); (err != nil) != tt.wantErr {
 				t.Errorf("LinePatch.Apply() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -0,0 +1,184 @@
+package filepatchers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// FileChange is the content of a file before and after patching.
+type FileChange struct {
+	File   string
+	Before []byte
+	After  []byte
+}
+
+// Recorder is a repository that records the changes that are written through it, such that the changes can be
+// presented as unified diff.
+type Recorder struct {
+	repo   Repository
+	before map[string][]byte
+	after  map[string][]byte
+}
+
+func NewRecorder(repo Repository) *Recorder {
+	return &Recorder{
+		repo:   repo,
+		before: map[string][]byte{},
+		after:  map[string][]byte{},
+	}
+}
+
+func (r *Recorder) ReadFile(ctx context.Context, path string) ([]byte, error) {
+	data, err := r.repo.ReadFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := r.before[path]; !ok {
+		r.before[path] = bytes.Clone(data)
+	}
+
+	return data, nil
+}
+
+func (r *Recorder) WriteFile(ctx context.Context, path string, data []byte) error {
+	if _, ok := r.before[path]; !ok {
+		// files that do not exist yet are recorded as empty
+		before, _ := r.repo.ReadFile(ctx, path)
+		r.before[path] = bytes.Clone(before)
+	}
+
+	err := r.repo.WriteFile(ctx, path, data)
+	if err != nil {
+		return err
+	}
+
+	r.after[path] = bytes.Clone(data)
+
+	return nil
+}
+
+func (r *Recorder) ListFiles(ctx context.Context, dir string) ([]string, error) {
+	return r.repo.ListFiles(ctx, dir)
+}
+
+// ReadSubmodule reads the commit of a submodule in case the recorded repository supports submodules.
+func (r *Recorder) ReadSubmodule(path string) (string, error) {
+	sr, ok := r.repo.(SubmoduleRepository)
+	if !ok {
+		return "", fmt.Errorf("repository does not support updating submodules, a cloned repository is required")
+	}
+
+	commit, err := sr.ReadSubmodule(path)
+	if err != nil {
+		return "", err
+	}
+
+	if _, ok := r.before[path]; !ok {
+		r.before[path] = submoduleContent(commit)
+	}
+
+	return commit, nil
+}
+
+// WriteSubmodule moves a submodule in case the recorded repository supports submodules, the change is recorded
+// in the way git presents it in diffs.
+func (r *Recorder) WriteSubmodule(path, commit string) error {
+	sr, ok := r.repo.(SubmoduleRepository)
+	if !ok {
+		return fmt.Errorf("repository does not support updating submodules, a cloned repository is required")
+	}
+
+	if _, err := r.ReadSubmodule(path); err != nil {
+		return err
+	}
+
+	err := sr.WriteSubmodule(path, commit)
+	if err != nil {
+		return err
+	}
+
+	r.after[path] = submoduleContent(commit)
+
+	return nil
+}
+
+func submoduleContent(commit string) []byte {
+	return []byte("Subproject commit " + commit + "\n")
+}
+
+// Changes returns the files whose content was changed, sorted by their path.
+func (r *Recorder) Changes() []FileChange {
+	var changes []FileChange
+	for path, after := range r.after {
+		if bytes.Equal(r.before[path], after) {
+			continue
+		}
+
+		changes = append(changes, FileChange{
+			File:   path,
+			Before: r.before[path],
+			After:  after,
+		})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].File < changes[j].File
+	})
+
+	return changes
+}
+
+// Diff renders the recorded changes as unified diff.
+func (r *Recorder) Diff() (string, error) {
+	var res strings.Builder
+
+	for _, c := range r.Changes() {
+		diff, err := UnifiedDiff(c.File, c.Before, c.After)
+		if err != nil {
+			return "", err
+		}
+
+		res.WriteString(diff)
+	}
+
+	return res.String(), nil
+}
+
+// UnifiedDiff renders the change of a file as unified diff with three lines of context.
+func UnifiedDiff(file string, before, after []byte) (string, error) {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(before),
+		B:        splitLines(after),
+		FromFile: "a/" + file,
+		ToFile:   "b/" + file,
+		Context:  3,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error rendering diff of %s: %w", file, err)
+	}
+
+	return diff, nil
+}
+
+// splitLines splits the content into lines that keep their line break, difflib.SplitLines adds an additional empty line instead
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+
+	// the missing line break at the end of the file is not rendered
+	lines[len(lines)-1] += "\n"
+
+	return lines
+}
@@ -0,0 +1,76 @@
+package filepatchers
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRecorder_Diff(t *testing.T) {
+	tests := []struct {
+		name     string
+		p        Patcher
+		repo     Repository
+		newValue string
+		want     string
+	}{
+		{
+			name: "diff of a patched file",
+			p:    YAMLPathPatch{file: "release.yaml", yamlPath: "docker-images.metal-api.tag", versionCompare: true, mode: YAMLPatchModeNode},
+			repo: testRepository{
+				"release.yaml": `---
+docker-images:
+  metal-api:
+    name: ghcr.io/metal-stack/metal-api
+    tag: v0.37.2
+  metal-console:
+    tag: v0.7.1
+`,
+			},
+			newValue: "v0.38.0",
+			want: `--- a/release.yaml
++++ b/release.yaml
+@@ -2,6 +2,6 @@
+ docker-images:
+   metal-api:
+     name: ghcr.io/metal-stack/metal-api
+-    tag: v0.37.2
++    tag: v0.38.0
+   metal-console:
+     tag: v0.7.1
+`,
+		},
+		{
+			name:     "no diff without changes",
+			p:        YAMLPathPatch{file: "release.yaml", yamlPath: "tag", versionCompare: true, mode: YAMLPatchModeNode},
+			repo:     testRepository{"release.yaml": "tag: v0.38.0\n"},
+			newValue: "v0.38.0",
+			want:     "",
+		},
+		{
+			name: "diff of a moved submodule",
+			p:    SubmodulePatch{path: "vendor/metal-api", resolveTag: func(_, _ string) (string, error) { return "0123456789abcdef0123456789abcdef01234567", nil }},
+			repo: testSubmoduleRepository{
+				testRepository: testRepository{
+					".gitmodules": "[submodule \"vendor/metal-api\"]\n\tpath = vendor/metal-api\n\turl = https://github.com/metal-stack/metal-api.git\n",
+				},
+				submodules: map[string]string{"vendor/metal-api": "0000000000000000000000000000000000000000"},
+			},
+			newValue: "v0.38.0",
+			want: `--- a/vendor/metal-api
++++ b/vendor/metal-api
+@@ -1 +1 @@
+-Subproject commit 0000000000000000000000000000000000000000
++Subproject commit 0123456789abcdef0123456789abcdef01234567
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := applyDiff(t, tt.p, tt.repo, tt.newValue)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Recorder.Diff() diff: %v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package filepatchers
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+
+	"github.com/metal-stack/metal-robot/pkg/config"
+	"github.com/metal-stack/metal-robot/pkg/utils"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	RegexPatchOccurrenceFirst string = "first"
+	RegexPatchOccurrenceAll   string = "all"
+)
+
+type RegexPatch struct {
+	file          string
+	regex         *regexp.Regexp
+	group         *string
+	template      *string
+	occurrence    string
+	failOnNoMatch bool
+}
+
+func newRegexPatch(rawConfig map[string]any) (*RegexPatch, error) {
+	var typedConfig config.RegexPatchConfig
+	err := mapstructure.Decode(rawConfig, &typedConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	regex, err := regexp.Compile(typedConfig.Regex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex: %w", err)
+	}
+
+	p := RegexPatch{
+		file:          typedConfig.File,
+		regex:         regex,
+		group:         typedConfig.Group,
+		template:      typedConfig.Template,
+		occurrence:    RegexPatchOccurrenceAll,
+		failOnNoMatch: true,
+	}
+
+	if typedConfig.Occurrence != nil {
+		p.occurrence = *typedConfig.Occurrence
+	}
+
+	if typedConfig.FailOnNoMatch != nil {
+		p.failOnNoMatch = *typedConfig.FailOnNoMatch
+	}
+
+	err = p.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (p RegexPatch) Apply(cr ContentReader, cw ContentWriter, newValue string, data utils.TemplateData) error {
+	content, err := cr(p.file)
+	if err != nil {
+		return fmt.Errorf("error reading patch file %w", err)
+	}
+
+	n := -1
+	if p.occurrence == RegexPatchOccurrenceFirst {
+		n = 1
+	}
+
+	matches := p.regex.FindAllSubmatchIndex(content, n)
+	if len(matches) == 0 {
+		if p.failOnNoMatch {
+			return fmt.Errorf("regex %q did not match in %s", p.regex.String(), p.file)
+		}
+		return nil
+	}
+
+	groups := []int{0}
+	if p.group != nil {
+		groups = p.groupIndexes()
+	}
+
+	var (
+		res  bytes.Buffer
+		last = 0
+	)
+
+	for _, match := range matches {
+		start, end := -1, -1
+		for _, group := range groups {
+			// a group name can occur multiple times in alternations, only one of them participates in the match
+			if match[2*group] >= 0 {
+				start, end = match[2*group], match[2*group+1]
+				break
+			}
+		}
+		if start < 0 {
+			continue
+		}
+
+		value := newValue
+		if p.template != nil {
+			value, err = renderTemplate(*p.template, newValue, string(content[start:end]), data)
+			if err != nil {
+				return err
+			}
+		}
+
+		res.Write(content[last:start])
+		res.WriteString(value)
+		last = end
+	}
+
+	res.Write(content[last:])
+
+	err = cw(p.file, res.Bytes())
+	if err != nil {
+		return fmt.Errorf("error writing patch file %w", err)
+	}
+
+	return nil
+}
+
+func (p RegexPatch) groupIndexes() []int {
+	var indexes []int
+	for i, name := range p.regex.SubexpNames() {
+		if p.group != nil && name == *p.group {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+func (p RegexPatch) Validate() error {
+	if p.file == "" {
+		return fmt.Errorf("file must be specified")
+	}
+	if p.regex == nil || p.regex.String() == "" {
+		return fmt.Errorf("regex must be specified")
+	}
+	if p.group == nil && p.template == nil {
+		return fmt.Errorf("either group or template must be specified")
+	}
+	if p.group != nil && p.regex.SubexpIndex(*p.group) < 0 {
+		return fmt.Errorf("regex does not contain a named group %q", *p.group)
+	}
+	switch p.occurrence {
+	case RegexPatchOccurrenceFirst, RegexPatchOccurrenceAll:
+	default:
+		return fmt.Errorf("occurrence must be one of %s or %s", RegexPatchOccurrenceFirst, RegexPatchOccurrenceAll)
+	}
+	if err := validateTemplate("template", p.template); err != nil {
+		return err
+	}
+	return nil
+}
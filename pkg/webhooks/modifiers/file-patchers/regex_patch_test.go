@@ -0,0 +1,157 @@
+package filepatchers
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-robot/pkg/utils"
+)
+
+func TestRegexPatch_Apply(t *testing.T) {
+	input := `FROM golang:1.22 AS builder
+ARG METAL_API_VERSION=v0.37.2
+RUN curl -L https://github.com/metal-stack/metal-api/releases/download/v0.37.2/metal-api -o /metal-api
+RUN echo v0.37.2
+`
+	tests := []struct {
+		name    string
+		config  map[string]any
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "replace named group in all occurrences",
+			config: map[string]any{
+				"file":  "Dockerfile",
+				"regex": `metal-api/releases/download/(?P<version>v[^/]+)/|METAL_API_VERSION=(?P<version>\S+)`,
+				"group": "version",
+			},
+			input: input,
+			want: `FROM golang:1.22 AS builder
+ARG METAL_API_VERSION=v0.38.0
+RUN curl -L https://github.com/metal-stack/metal-api/releases/download/v0.38.0/metal-api -o /metal-api
+RUN echo v0.37.2
+`,
+		},
+		{
+			name: "replace named group",
+			config: map[string]any{
+				"file":  "Dockerfile",
+				"regex": `METAL_API_VERSION=(?P<version>\S+)`,
+				"group": "version",
+			},
+			input: input,
+			want: `FROM golang:1.22 AS builder
+ARG METAL_API_VERSION=v0.38.0
+RUN curl -L https://github.com/metal-stack/metal-api/releases/download/v0.37.2/metal-api -o /metal-api
+RUN echo v0.37.2
+`,
+		},
+		{
+			name: "replace first full match with template",
+			config: map[string]any{
+				"file":       "Dockerfile",
+				"regex":      `v\d+\.\d+\.\d+`,
+				"template":   "%s",
+				"occurrence": "first",
+			},
+			input: input,
+			want: `FROM golang:1.22 AS builder
+ARG METAL_API_VERSION=v0.38.0
+RUN curl -L https://github.com/metal-stack/metal-api/releases/download/v0.37.2/metal-api -o /metal-api
+RUN echo v0.37.2
+`,
+		},
+		{
+			name: "replace all full matches with template",
+			config: map[string]any{
+				"file":     "Dockerfile",
+				"regex":    `echo v\S+`,
+				"template": "echo %s",
+			},
+			input: input,
+			want: `FROM golang:1.22 AS builder
+ARG METAL_API_VERSION=v0.37.2
+RUN curl -L https://github.com/metal-stack/metal-api/releases/download/v0.37.2/metal-api -o /metal-api
+RUN echo v0.38.0
+`,
+		},
+		{
+			name: "no match fails",
+			config: map[string]any{
+				"file":  "Dockerfile",
+				"regex": `METAL_CORE_VERSION=(?P<version>\S+)`,
+				"group": "version",
+			},
+			input:   input,
+			wantErr: true,
+		},
+		{
+			name: "no match does not fail if configured",
+			config: map[string]any{
+				"file":             "Dockerfile",
+				"regex":            `METAL_CORE_VERSION=(?P<version>\S+)`,
+				"group":            "version",
+				"fail-on-no-match": false,
+			},
+			input: input,
+			want:  "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := newRegexPatch(tt.config)
+			if err != nil {
+				t.Fatalf("newRegexPatch() error = %v", err)
+			}
+
+			var got string
+			cn := func(file string) ([]byte, error) {
+				return []byte(tt.input), nil
+			}
+			cw := func(file string, content []byte) error {
+				got = string(content)
+				return nil
+			}
+			if err := p.Apply(cn, cw, "v0.38.0", utils.NewTemplateData("v0.38.0")); (err != nil) != tt.wantErr {
+				t.Errorf("RegexPatch.Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("RegexPatch.Apply() diff: %v", diff)
+			}
+		})
+	}
+}
+
+func TestRegexPatch_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]any
+	}{
+		{
+			name:   "invalid regex",
+			config: map[string]any{"file": "a", "regex": "(", "group": "v"},
+		},
+		{
+			name:   "unknown group",
+			config: map[string]any{"file": "a", "regex": "(?P<version>.*)", "group": "v"},
+		},
+		{
+			name:   "neither group nor template",
+			config: map[string]any{"file": "a", "regex": "(?P<version>.*)"},
+		},
+		{
+			name:   "invalid occurrence",
+			config: map[string]any{"file": "a", "regex": "(?P<version>.*)", "group": "version", "occurrence": "last"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newRegexPatch(tt.config)
+			if err == nil {
+				t.Errorf("newRegexPatch() expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package filepatchers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/metal-stack/metal-robot/pkg/config"
+	"github.com/metal-stack/metal-robot/pkg/git"
+	"github.com/metal-stack/metal-robot/pkg/utils"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	gitmodulesFile = ".gitmodules"
+)
+
+// SubmodulePatch moves a submodule to the commit of the release tag in the submodule remote.
+type SubmodulePatch struct {
+	path   string
+	url    *string
+	branch *string
+
+	// resolveTag returns the commit of a tag, it can be replaced for testing purposes
+	resolveTag func(repoURL, tag string) (string, error)
+}
+
+func newSubmodulePatch(rawConfig map[string]any) (*SubmodulePatch, error) {
+	var typedConfig config.SubmodulePatchConfig
+	err := mapstructure.Decode(rawConfig, &typedConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	p := SubmodulePatch{
+		path:       strings.Trim(typedConfig.Path, "/"),
+		url:        typedConfig.URL,
+		branch:     typedConfig.Branch,
+		resolveTag: git.ResolveTag,
+	}
+
+	err = p.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (p SubmodulePatch) Apply(_ ContentReader, _ ContentWriter, _ string, _ utils.TemplateData) error {
+	return fmt.Errorf("patching submodules requires access to the repository")
+}
+
+// ApplyRepository points the gitlink of the submodule to the commit of the given tag
+func (p SubmodulePatch) ApplyRepository(ctx context.Context, _ *slog.Logger, repo Repository, newValue string, _ utils.TemplateData) error {
+	sr, ok := repo.(SubmoduleRepository)
+	if !ok {
+		return fmt.Errorf("repository does not support updating submodules, a cloned repository is required")
+	}
+
+	content, err := sr.ReadFile(ctx, gitmodulesFile)
+	if err != nil {
+		return fmt.Errorf("error reading %s %w", gitmodulesFile, err)
+	}
+
+	modules := gitconfig.NewModules()
+	err = modules.Unmarshal(content)
+	if err != nil {
+		return fmt.Errorf("error parsing %s: %w", gitmodulesFile, err)
+	}
+
+	var submodule *gitconfig.Submodule
+	for _, s := range modules.Submodules {
+		if s.Path == p.path {
+			submodule = s
+			break
+		}
+	}
+
+	if submodule == nil {
+		return fmt.Errorf("submodule %s not found in %s", p.path, gitmodulesFile)
+	}
+
+	url := submodule.URL
+	if p.url != nil {
+		url = *p.url
+	}
+
+	if strings.HasPrefix(url, "./") || strings.HasPrefix(url, "../") {
+		return fmt.Errorf("relative submodule url %q cannot be resolved, the url needs to be configured", url)
+	}
+
+	commit, err := p.resolveTag(url, newValue)
+	if err != nil {
+		return fmt.Errorf("error resolving tag %s of submodule %s: %w", newValue, p.path, err)
+	}
+
+	current, err := sr.ReadSubmodule(p.path)
+	if err != nil {
+		return err
+	}
+
+	if current != commit {
+		err = sr.WriteSubmodule(p.path, commit)
+		if err != nil {
+			return err
+		}
+	}
+
+	if p.branch == nil || submodule.Branch == *p.branch {
+		return nil
+	}
+
+	submodule.Branch = *p.branch
+
+	content, err = modules.Marshal()
+	if err != nil {
+		return fmt.Errorf("error encoding %s: %w", gitmodulesFile, err)
+	}
+
+	err = sr.WriteFile(ctx, gitmodulesFile, content)
+	if err != nil {
+		return fmt.Errorf("error writing patch file %w", err)
+	}
+
+	return nil
+}
+
+func (p SubmodulePatch) Validate() error {
+	if p.path == "" {
+		return fmt.Errorf("path must be specified")
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package filepatchers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-robot/pkg/utils"
+)
+
+type testSubmoduleRepository struct {
+	testRepository
+	submodules map[string]string
+}
+
+func (r testSubmoduleRepository) ReadSubmodule(path string) (string, error) {
+	commit, ok := r.submodules[path]
+	if !ok {
+		return "", fmt.Errorf("%s is not a submodule", path)
+	}
+	return commit, nil
+}
+
+func (r testSubmoduleRepository) WriteSubmodule(path, commit string) error {
+	r.submodules[path] = commit
+	return nil
+}
+
+func TestSubmodulePatch_ApplyRepository(t *testing.T) {
+	const (
+		oldCommit = "0000000000000000000000000000000000000000"
+		newCommit = "0123456789abcdef0123456789abcdef01234567"
+	)
+
+	gitmodules := `[submodule "vendor/metal-api"]
+	path = vendor/metal-api
+	url = https://github.com/metal-stack/metal-api.git
+`
+
+	tests := []struct {
+		name           string
+		p              SubmodulePatch
+		repo           Repository
+		newValue       string
+		wantSubmodules map[string]string
+		wantFiles      testRepository
+		wantErr        bool
+	}{
+		{
+			name: "move submodule to tag",
+			p:    SubmodulePatch{path: "vendor/metal-api"},
+			repo: testSubmoduleRepository{
+				testRepository: testRepository{".gitmodules": gitmodules},
+				submodules:     map[string]string{"vendor/metal-api": oldCommit},
+			},
+			newValue:       "v0.38.0",
+			wantSubmodules: map[string]string{"vendor/metal-api": newCommit},
+			wantFiles:      testRepository{".gitmodules": gitmodules},
+		},
+		{
+			name: "move submodule and set branch",
+			p:    SubmodulePatch{path: "vendor/metal-api", branch: new("release")},
+			repo: testSubmoduleRepository{
+				testRepository: testRepository{".gitmodules": gitmodules},
+				submodules:     map[string]string{"vendor/metal-api": oldCommit},
+			},
+			newValue:       "v0.38.0",
+			wantSubmodules: map[string]string{"vendor/metal-api": newCommit},
+			wantFiles: testRepository{".gitmodules": `[submodule "vendor/metal-api"]
+	path = vendor/metal-api
+	url = https://github.com/metal-stack/metal-api.git
+	branch = release
+`},
+		},
+		{
+			name: "unknown submodule",
+			p:    SubmodulePatch{path: "vendor/metal-core"},
+			repo: testSubmoduleRepository{
+				testRepository: testRepository{".gitmodules": gitmodules},
+				submodules:     map[string]string{"vendor/metal-api": oldCommit},
+			},
+			newValue:       "v0.38.0",
+			wantSubmodules: map[string]string{"vendor/metal-api": oldCommit},
+			wantFiles:      testRepository{".gitmodules": gitmodules},
+			wantErr:        true,
+		},
+		{
+			name:      "repository without submodule support",
+			p:         SubmodulePatch{path: "vendor/metal-api"},
+			repo:      testRepository{".gitmodules": gitmodules},
+			newValue:  "v0.38.0",
+			wantFiles: testRepository{".gitmodules": gitmodules},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.p.resolveTag = func(repoURL, tag string) (string, error) {
+				if repoURL != "https://github.com/metal-stack/metal-api.git" {
+					return "", fmt.Errorf("unexpected repository url: %s", repoURL)
+				}
+				return newCommit, nil
+			}
+			if err := tt.p.ApplyRepository(context.Background(), slog.New(slog.DiscardHandler), tt.repo, tt.newValue, utils.NewTemplateData(tt.newValue)); (err != nil) != tt.wantErr {
+				t.Errorf("SubmodulePatch.ApplyRepository() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			files, submodules := tt.repo, map[string]string(nil)
+			if sr, ok := tt.repo.(testSubmoduleRepository); ok {
+				files, submodules = sr.testRepository, sr.submodules
+			}
+			if diff := cmp.Diff(tt.wantFiles, files); diff != "" {
+				t.Errorf("SubmodulePatch.ApplyRepository() files diff: %v", diff)
+			}
+			if diff := cmp.Diff(tt.wantSubmodules, submodules); diff != "" {
+				t.Errorf("SubmodulePatch.ApplyRepository() submodules diff: %v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package filepatchers
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/metal-stack/metal-robot/pkg/config"
+	"github.com/metal-stack/metal-robot/pkg/utils"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pelletier/go-toml/v2/unstable"
+)
+
+type TOMLPathPatch struct {
+	file           string
+	tomlPath       string
+	template       *string
+	versionCompare bool
+	policy         *utils.VersionPolicy
+}
+
+func newTOMLPathPatch(rawConfig map[string]any) (*TOMLPathPatch, error) {
+	var typedConfig config.TOMLPathPatchConfig
+	err := mapstructure.Decode(rawConfig, &typedConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	p := TOMLPathPatch{
+		file:           typedConfig.File,
+		tomlPath:       typedConfig.TOMLPath,
+		template:       typedConfig.Template,
+		versionCompare: true,
+	}
+
+	if typedConfig.VersionCompare != nil {
+		p.versionCompare = *typedConfig.VersionCompare
+	}
+
+	p.policy, err = utils.NewVersionPolicy(typedConfig.VersionPolicy, utils.PrereleaseAllow)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// Apply replaces the string at the toml path in place, the formatting of the rest of the file is preserved
+func (p TOMLPathPatch) Apply(cr ContentReader, cw ContentWriter, newValue string, data utils.TemplateData) error {
+	content, err := cr(p.file)
+	if err != nil {
+		return fmt.Errorf("error reading patch file %w", err)
+	}
+
+	old, oldErr := GetTOML(content, p.tomlPath)
+
+	if p.versionCompare && oldErr != nil {
+		return fmt.Errorf("error retrieving toml path from file %w", oldErr)
+	}
+
+	allowed, err := allowsUpdate(old, newValue, p.template != nil, p.versionCompare, p.policy)
+	if err != nil {
+		return err
+	}
+
+	if !allowed {
+		return nil
+	}
+
+	if p.template != nil {
+		newValue, err = renderTemplate(*p.template, newValue, old, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	content, err = setTOML(content, p.tomlPath, newValue)
+	if err != nil {
+		return err
+	}
+
+	err = cw(p.file, content)
+	if err != nil {
+		return fmt.Errorf("error writing patch file %w", err)
+	}
+
+	return nil
+}
+
+// GetTOML returns the string value at the given path in dot notation
+func GetTOML(data []byte, path string) (string, error) {
+	node, err := findTOMLString(data, path)
+	if err != nil {
+		return "", err
+	}
+
+	return string(node.Data), nil
+}
+
+func setTOML(data []byte, path string, value string) ([]byte, error) {
+	node, err := findTOMLString(data, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		start       = int(node.Raw.Offset)
+		end         = start + int(node.Raw.Length)
+		replacement = tomlQuote(value)
+	)
+
+	if data[start] == '\'' && !strings.ContainsAny(value, "'\n") {
+		replacement = "'" + value + "'"
+	}
+
+	var res bytes.Buffer
+	res.Write(data[:start])
+	res.WriteString(replacement)
+	res.Write(data[end:])
+
+	return res.Bytes(), nil
+}
+
+// findTOMLString finds the string node at the given path. Array elements and array tables are addressed by their index.
+func findTOMLString(data []byte, path string) (*unstable.Node, error) {
+	var (
+		p      = unstable.Parser{}
+		want   = strings.Join(splitPath(path), "\x00")
+		prefix []string
+		tables = map[string]int{}
+	)
+
+	p.Reset(data)
+
+	var find func(n *unstable.Node, key []string) *unstable.Node
+	find = func(n *unstable.Node, key []string) *unstable.Node {
+		switch n.Kind {
+		case unstable.String:
+			if strings.Join(key, "\x00") == want {
+				return n
+			}
+		case unstable.InlineTable:
+			it := n.Children()
+			for it.Next() {
+				kv := it.Node()
+				if found := find(kv.Value(), append(slices.Clone(key), tomlKey(kv)...)); found != nil {
+					return found
+				}
+			}
+		case unstable.Array:
+			it := n.Children()
+			for i := 0; it.Next(); i++ {
+				if found := find(it.Node(), append(slices.Clone(key), strconv.Itoa(i))); found != nil {
+					return found
+				}
+			}
+		}
+		return nil
+	}
+
+	for p.NextExpression() {
+		e := p.Expression()
+
+		switch e.Kind {
+		case unstable.Table:
+			prefix = tomlKey(e)
+		case unstable.ArrayTable:
+			key := tomlKey(e)
+			name := strings.Join(key, "\x00")
+			prefix = append(key, strconv.Itoa(tables[name]))
+			tables[name]++
+		case unstable.KeyValue:
+			if found := find(e.Value(), append(slices.Clone(prefix), tomlKey(e)...)); found != nil {
+				return found, nil
+			}
+		}
+	}
+
+	if err := p.Error(); err != nil {
+		return nil, fmt.Errorf("error parsing toml: %w", err)
+	}
+
+	return nil, fmt.Errorf("string value not found in toml: %v", path)
+}
+
+func tomlKey(n *unstable.Node) []string {
+	var key []string
+	it := n.Key()
+	for it.Next() {
+		key = append(key, string(it.Node().Data))
+	}
+	return key
+}
+
+// tomlQuote returns the value as toml basic string
+func tomlQuote(value string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range value {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString("\\n")
+		case r == '\t':
+			b.WriteString("\\t")
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, "\\u%04X", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func (p TOMLPathPatch) Validate() error {
+	if p.file == "" {
+		return fmt.Errorf("file must be specified")
+	}
+	if p.tomlPath == "" {
+		return fmt.Errorf("toml-path must be specified")
+	}
+	if err := validateTemplate("template", p.template); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package filepatchers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-robot/pkg/utils"
+)
+
+func TestTOMLPathPatch_Apply(t *testing.T) {
+	input := `# metal-stack python client
+[tool.poetry]
+name = "metal-python"
+version = "0.14.0"  # bumped on release
+
+[tool.poetry.dependencies]
+python = "^3.9"
+metal-api = { version = '0.37.2', optional = true }
+
+[[tool.poetry.source]]
+name = "pypi"
+url = "https://pypi.org/simple/"
+
+[[tool.poetry.source]]
+name = "metal-stack"
+url = "https://pypi.metal-stack.io/v0.14.0/simple/"
+`
+	tpl := "https://pypi.metal-stack.io/%s/simple/"
+
+	tests := []struct {
+		name     string
+		p        TOMLPathPatch
+		newValue string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "replace a path and keep formatting",
+			p:        TOMLPathPatch{file: "pyproject.toml", tomlPath: "tool.poetry.version", versionCompare: true},
+			newValue: "0.15.0",
+			want:     strings.Replace(input, `version = "0.14.0"  # bumped`, `version = "0.15.0"  # bumped`, 1),
+		},
+		{
+			name:     "replace in inline table and keep literal string",
+			p:        TOMLPathPatch{file: "pyproject.toml", tomlPath: "tool.poetry.dependencies.metal-api.version", versionCompare: true},
+			newValue: "0.38.0",
+			want:     strings.Replace(input, `version = '0.37.2'`, `version = '0.38.0'`, 1),
+		},
+		{
+			name:     "replace in array table with template",
+			p:        TOMLPathPatch{file: "pyproject.toml", tomlPath: "tool.poetry.source.1.url", template: &tpl, versionCompare: true},
+			newValue: "v0.15.0",
+			want:     strings.Replace(input, "v0.14.0/simple", "v0.15.0/simple", 1),
+		},
+		{
+			name:     "change nothing on lower version",
+			p:        TOMLPathPatch{file: "pyproject.toml", tomlPath: "tool.poetry.version", versionCompare: true},
+			newValue: "0.13.0",
+			want:     "",
+		},
+		{
+			name:     "path not found",
+			p:        TOMLPathPatch{file: "pyproject.toml", tomlPath: "tool.poetry.source.2.url"},
+			newValue: "0.15.0",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			cn := func(file string) ([]byte, error) {
+				return []byte(input), nil
+			}
+			cw := func(file string, content []byte) error {
+				got = string(content)
+				return nil
+			}
+			if err := tt.p.Apply(cn, cw, tt.newValue, utils.NewTemplateData(tt.newValue)); (err != nil) != tt.wantErr {
+				t.Errorf("TOMLPathPatch.Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("TOMLPathPatch.Apply() diff: %v", diff)
+			}
+		})
+	}
+}
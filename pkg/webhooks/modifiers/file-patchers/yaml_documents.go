@@ -0,0 +1,116 @@
+package filepatchers
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// yamlDocument is a single document of a multi-document yaml file. The separator line is kept apart from
+// the body, such that documents can be patched individually and joined again without altering the rest of the file.
+type yamlDocument struct {
+	separator []byte
+	body      []byte
+}
+
+// splitYAMLDocuments splits the raw content at document separators (lines starting with ---)
+func splitYAMLDocuments(data []byte) []*yamlDocument {
+	var (
+		docs      []*yamlDocument
+		separator []byte
+		bodyStart = 0
+		pos       = 0
+	)
+
+	for pos < len(data) {
+		end := bytes.IndexByte(data[pos:], '\n')
+		if end < 0 {
+			end = len(data)
+		} else {
+			end += pos + 1
+		}
+
+		if line := data[pos:end]; isDocumentSeparator(line) {
+			if separator != nil || pos > bodyStart {
+				docs = append(docs, &yamlDocument{separator: separator, body: data[bodyStart:pos]})
+			}
+			separator = line
+			bodyStart = end
+		}
+
+		pos = end
+	}
+
+	return append(docs, &yamlDocument{separator: separator, body: data[bodyStart:]})
+}
+
+func isDocumentSeparator(line []byte) bool {
+	if !bytes.HasPrefix(line, []byte("---")) {
+		return false
+	}
+	rest := line[3:]
+	return len(bytes.TrimSpace(rest)) == 0 || rest[0] == ' ' || rest[0] == '\t'
+}
+
+func joinYAMLDocuments(docs []*yamlDocument) []byte {
+	var res bytes.Buffer
+	for _, doc := range docs {
+		res.Write(doc.separator)
+		res.Write(doc.body)
+	}
+	return res.Bytes()
+}
+
+// isEmpty returns true if the document does not contain anything else than comments and whitespace
+func (d *yamlDocument) isEmpty() bool {
+	for line := range bytes.Lines(d.body) {
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) > 0 && trimmed[0] != '#' {
+			return false
+		}
+	}
+	return true
+}
+
+// matches returns true if the document contains all the given values at the given paths
+func (d *yamlDocument) matches(match map[string]string) bool {
+	for path, want := range match {
+		got, err := GetYAML(d.body, path)
+		if err != nil || got != want {
+			return false
+		}
+	}
+	return true
+}
+
+// selectYAMLDocuments returns the non-empty documents selected by index and matcher. If no selection criteria are given,
+// all non-empty documents are returned.
+func selectYAMLDocuments(docs []*yamlDocument, index *int, match map[string]string) ([]*yamlDocument, error) {
+	var (
+		selected []*yamlDocument
+		i        = -1
+	)
+
+	for _, doc := range docs {
+		if doc.isEmpty() {
+			continue
+		}
+
+		i++
+
+		if index != nil && *index != i {
+			continue
+		}
+
+		if !doc.matches(match) {
+			continue
+		}
+
+		selected = append(selected, doc)
+	}
+
+	if index != nil && *index > i {
+		return nil, fmt.Errorf("document index %d out of range, file contains %d documents", *index, i+1)
+	}
+
+	return selected, nil
+}
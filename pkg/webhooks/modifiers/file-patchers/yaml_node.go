@@ -0,0 +1,221 @@
+package filepatchers
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// setYAMLNode replaces the scalar at the given path directly in the raw content. In contrast to setYAML everything else
+// in the document stays untouched, including comments, key order, indentation, quoting style and anchors.
+func setYAMLNode(data []byte, path string, value string) ([]byte, error) {
+	var root yaml.Node
+	err := yaml.Unmarshal(data, &root)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing yaml: %w", err)
+	}
+
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return nil, fmt.Errorf("yaml document is empty")
+	}
+
+	node, err := findYAMLNode(root.Content[0], splitPath(path))
+	if err != nil {
+		return nil, err
+	}
+
+	return replaceScalar(data, node, value)
+}
+
+// splitPath splits a path in gjson dot notation, dots can be escaped with a backslash
+func splitPath(path string) []string {
+	var (
+		keys    []string
+		current strings.Builder
+	)
+
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case c == '\\' && i+1 < len(path):
+			i++
+			current.WriteByte(path[i])
+		case c == '.':
+			keys = append(keys, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(c)
+		}
+	}
+
+	return append(keys, current.String())
+}
+
+func findYAMLNode(node *yaml.Node, keys []string) (*yaml.Node, error) {
+	for i, key := range keys {
+		for node.Kind == yaml.AliasNode {
+			node = node.Alias
+		}
+
+		var next *yaml.Node
+
+		switch node.Kind {
+		case yaml.MappingNode:
+			for j := 0; j+1 < len(node.Content); j += 2 {
+				if node.Content[j].Value == key {
+					next = node.Content[j+1]
+				}
+			}
+		case yaml.SequenceNode:
+			idx, err := strconv.Atoi(key)
+			if err == nil && idx >= 0 && idx < len(node.Content) {
+				next = node.Content[idx]
+			}
+		}
+
+		if next == nil {
+			return nil, fmt.Errorf("path not found in yaml: %s", strings.Join(keys[:i+1], "."))
+		}
+
+		node = next
+	}
+
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	if node.Kind != yaml.ScalarNode {
+		return nil, fmt.Errorf("path in yaml does not point to a scalar value: %s", strings.Join(keys, "."))
+	}
+
+	return node, nil
+}
+
+func replaceScalar(data []byte, node *yaml.Node, value string) ([]byte, error) {
+	start, err := offset(data, node.Line, node.Column)
+	if err != nil {
+		return nil, err
+	}
+
+	start = skipProperties(data, start)
+
+	var (
+		end         int
+		replacement string
+	)
+
+	switch {
+	case node.Style&yaml.DoubleQuotedStyle != 0:
+		end, err = quotedEnd(data, start, '"')
+		replacement = strconv.Quote(value)
+	case node.Style&yaml.SingleQuotedStyle != 0:
+		end, err = quotedEnd(data, start, '\'')
+		replacement = "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	case node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+		return nil, fmt.Errorf("replacing block scalars is not supported")
+	default:
+		end = start + len(node.Value)
+		if !bytes.HasPrefix(data[start:], []byte(node.Value)) {
+			return nil, fmt.Errorf("replacing multi-line plain scalars is not supported")
+		}
+
+		if node.Value == "" {
+			return nil, fmt.Errorf("replacing empty values is not supported")
+		}
+
+		tag := node.Tag
+		if tag == "!!null" {
+			tag = "!!str"
+		}
+
+		replacement = value
+		if !isPlainScalar(value, tag) {
+			replacement = strconv.Quote(value)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var res bytes.Buffer
+	res.Write(data[:start])
+	res.WriteString(replacement)
+	res.Write(data[end:])
+
+	return res.Bytes(), nil
+}
+
+// offset converts the line and column of a node, which are counted in characters, into a byte offset
+func offset(data []byte, line, column int) (int, error) {
+	pos := 0
+
+	for l := 1; l < line; l++ {
+		idx := bytes.IndexByte(data[pos:], '\n')
+		if idx < 0 {
+			return 0, fmt.Errorf("line %d out of range", line)
+		}
+		pos += idx + 1
+	}
+
+	for c := 1; c < column; c++ {
+		if pos >= len(data) {
+			return 0, fmt.Errorf("column %d out of range", column)
+		}
+		_, size := utf8.DecodeRune(data[pos:])
+		pos += size
+	}
+
+	return pos, nil
+}
+
+// skipProperties skips anchors and tags that are placed in front of a scalar
+func skipProperties(data []byte, pos int) int {
+	for pos < len(data) && (data[pos] == '&' || data[pos] == '!') {
+		for pos < len(data) && data[pos] != ' ' && data[pos] != '\t' {
+			pos++
+		}
+		for pos < len(data) && (data[pos] == ' ' || data[pos] == '\t') {
+			pos++
+		}
+	}
+	return pos
+}
+
+func quotedEnd(data []byte, start int, quote byte) (int, error) {
+	for i := start + 1; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			if quote == '"' {
+				i++
+			}
+		case quote:
+			if quote == '\'' && i+1 < len(data) && data[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i + 1, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unterminated quoted scalar")
+}
+
+// isPlainScalar returns true if the value can be written without quotes and is still resolved to the given tag
+func isPlainScalar(value, tag string) bool {
+	if value == "" || strings.ContainsAny(value, "\n#") || strings.TrimSpace(value) != value {
+		return false
+	}
+
+	var node yaml.Node
+	err := yaml.Unmarshal([]byte("v: "+value), &node)
+	if err != nil || len(node.Content) == 0 || len(node.Content[0].Content) != 2 {
+		return false
+	}
+
+	v := node.Content[0].Content[1]
+
+	return v.Kind == yaml.ScalarNode && v.Style == 0 && v.Value == value && v.Tag == tag
+}
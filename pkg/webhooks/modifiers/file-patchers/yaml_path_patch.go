@@ -2,9 +2,7 @@ package filepatchers
 
 import (
 	"fmt"
-	"strings"
 
-	"github.com/Masterminds/semver/v3"
 	"github.com/metal-stack/metal-robot/pkg/config"
 	"github.com/metal-stack/metal-robot/pkg/utils"
 	"github.com/mitchellh/mapstructure"
@@ -15,11 +13,22 @@ import (
 	"github.com/tidwall/sjson"
 )
 
+const (
+	// YAMLPatchModeConvert converts the yaml to json for patching, which strips comments and reorders keys
+	YAMLPatchModeConvert string = "convert"
+	// YAMLPatchModeNode only replaces the targeted scalar and leaves the rest of the file as it is
+	YAMLPatchModeNode string = "node"
+)
+
 type YAMLPathPatch struct {
 	file           string
 	yamlPath       string
 	template       *string
 	versionCompare bool
+	policy         *utils.VersionPolicy
+	mode           string
+	document       *int
+	documentMatch  map[string]string
 }
 
 func newYAMLPathPatch(rawConfig map[string]any) (*YAMLPathPatch, error) {
@@ -34,12 +43,24 @@ func newYAMLPathPatch(rawConfig map[string]any) (*YAMLPathPatch, error) {
 		yamlPath:       typedConfig.YAMLPath,
 		template:       typedConfig.Template,
 		versionCompare: true,
+		mode:           YAMLPatchModeConvert,
+		document:       typedConfig.Document,
+		documentMatch:  typedConfig.DocumentMatch,
 	}
 
 	if typedConfig.VersionCompare != nil {
 		p.versionCompare = *typedConfig.VersionCompare
 	}
 
+	p.policy, err = utils.NewVersionPolicy(typedConfig.VersionPolicy, utils.PrereleaseAllow)
+	if err != nil {
+		return nil, err
+	}
+
+	if typedConfig.Mode != nil {
+		p.mode = *typedConfig.Mode
+	}
+
 	err = p.Validate()
 	if err != nil {
 		return nil, err
@@ -48,49 +69,53 @@ func newYAMLPathPatch(rawConfig map[string]any) (*YAMLPathPatch, error) {
 	return &p, nil
 }
 
-func (p YAMLPathPatch) Apply(cn ContentReader, cw ContentWriter, newValue string) error {
+func (p YAMLPathPatch) Apply(cn ContentReader, cw ContentWriter, newValue string, data utils.TemplateData) error {
 	content, err := cn(p.file)
 	if err != nil {
 		return fmt.Errorf("error reading patch file %w", err)
 	}
 
-	if p.versionCompare {
-		trimmedValue := strings.TrimPrefix(newValue, "v")
+	docs := splitYAMLDocuments(content)
 
-		newVersion, err := semver.NewVersion(trimmedValue)
-		if err != nil {
-			return err
-		}
+	selected, err := selectYAMLDocuments(docs, p.document, p.documentMatch)
+	if err != nil {
+		return err
+	}
 
-		old, err := GetYAML(content, p.yamlPath)
-		if err != nil {
-			return fmt.Errorf("error retrieving yaml path from file %w", err)
-		}
+	if len(selected) == 0 {
+		return fmt.Errorf("no yaml document in %s matches the document selection", p.file)
+	}
 
-		if p.template != nil {
-			groups := utils.RegexCapture(utils.SemanticVersionMatcher, old)
-			old = groups["full_match"]
+	if len(selected) > 1 && p.document == nil && len(p.documentMatch) == 0 {
+		// without explicit selection only the documents that contain the path are patched
+		var candidates []*yamlDocument
+		for _, doc := range selected {
+			if _, err := GetYAML(doc.body, p.yamlPath); err == nil {
+				candidates = append(candidates, doc)
+			}
 		}
 
-		old = strings.TrimPrefix(old, "v")
-		oldVersion, err := semver.NewVersion(old)
-		if err == nil {
-			if !newVersion.GreaterThan(oldVersion) {
-				return nil
-			}
+		if len(candidates) == 0 {
+			return fmt.Errorf("path not found in any yaml document: %v", p.yamlPath)
 		}
+
+		selected = candidates
 	}
 
-	if p.template != nil {
-		newValue = fmt.Sprintf(*p.template, newValue)
+	patched := false
+	for _, doc := range selected {
+		ok, err := p.patch(doc, newValue, data)
+		if err != nil {
+			return err
+		}
+		patched = patched || ok
 	}
 
-	content, err = setYAML(content, p.yamlPath, newValue)
-	if err != nil {
-		return err
+	if !patched {
+		return nil
 	}
 
-	err = cw(p.file, content)
+	err = cw(p.file, joinYAMLDocuments(docs))
 	if err != nil {
 		return fmt.Errorf("error writing patch file %w", err)
 	}
@@ -98,6 +123,46 @@ func (p YAMLPathPatch) Apply(cn ContentReader, cw ContentWriter, newValue string
 	return nil
 }
 
+// patch sets the new value in the given document and returns false if the document was not patched because of the version comparison or policy
+func (p YAMLPathPatch) patch(doc *yamlDocument, newValue string, data utils.TemplateData) (bool, error) {
+	old, oldErr := GetYAML(doc.body, p.yamlPath)
+
+	if p.versionCompare && oldErr != nil {
+		return false, fmt.Errorf("error retrieving yaml path from file %w", oldErr)
+	}
+
+	allowed, err := allowsUpdate(old, newValue, p.template != nil, p.versionCompare, p.policy)
+	if err != nil {
+		return false, err
+	}
+
+	if !allowed {
+		return false, nil
+	}
+
+	var body []byte
+
+	if p.template != nil {
+		newValue, err = renderTemplate(*p.template, newValue, old, data)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if p.mode == YAMLPatchModeNode {
+		body, err = setYAMLNode(doc.body, p.yamlPath, newValue)
+	} else {
+		body, err = setYAML(doc.body, p.yamlPath, newValue)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	doc.body = body
+
+	return true, nil
+}
+
 func setYAML(data []byte, path string, value any) ([]byte, error) {
 	json, err := yamlconv.YAMLToJSON(data)
 	if err != nil {
@@ -139,5 +204,16 @@ func (p YAMLPathPatch) Validate() error {
 	if p.yamlPath == "" {
 		return fmt.Errorf("yaml-path must be specified")
 	}
+	if p.document != nil && *p.document < 0 {
+		return fmt.Errorf("document index must not be negative")
+	}
+	switch p.mode {
+	case "", YAMLPatchModeConvert, YAMLPatchModeNode:
+	default:
+		return fmt.Errorf("unsupported yaml patch mode: %s", p.mode)
+	}
+	if err := validateTemplate("template", p.template); err != nil {
+		return err
+	}
 	return nil
 }
@@ -1,9 +1,13 @@
 package filepatchers
 
 import (
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-robot/pkg/config"
+	"github.com/metal-stack/metal-robot/pkg/utils"
 )
 
 func Test_setYAML(t *testing.T) {
@@ -157,6 +161,30 @@ func TestYAMLPathVersionPatches_Apply(t *testing.T) {
 			output:   "a: http://server.io/0.0.2.exe\n",
 			wantErr:  false,
 		},
+		{
+			name:     "change nothing on increment not allowed by the version policy",
+			p:        YAMLPathPatch{file: "example.yaml", yamlPath: "a", versionCompare: true, policy: versionPolicy(t, config.VersionPolicyConfig{Increments: []string{utils.IncrementMinor, utils.IncrementPatch}})},
+			newValue: "v1.0.0",
+			input:    "a: v0.1.0",
+			output:   "a: v0.1.0\n",
+			wantErr:  false,
+		},
+		{
+			name:     "change nothing on prerelease skipped by the version policy without version comparison",
+			p:        YAMLPathPatch{file: "example.yaml", yamlPath: "a", policy: versionPolicy(t, config.VersionPolicyConfig{Prerelease: new(utils.PrereleaseSkip)})},
+			newValue: "v0.2.0-rc.1",
+			input:    "a: v0.1.0",
+			output:   "a: v0.1.0\n",
+			wantErr:  false,
+		},
+		{
+			name:     "replace a path allowed by the version policy without version comparison",
+			p:        YAMLPathPatch{file: "example.yaml", yamlPath: "a", policy: versionPolicy(t, config.VersionPolicyConfig{Constraint: new("~0.1")})},
+			newValue: "v0.1.5",
+			input:    "a: v0.2.0",
+			output:   "a: v0.1.5\n",
+			wantErr:  false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -169,9 +197,242 @@ func TestYAMLPathVersionPatches_Apply(t *testing.T) {
 				}
 				return nil
 			}
-			if err := tt.p.Apply(cn, cw, tt.newValue); (err != nil) != tt.wantErr {
+			if err := tt.p.Apply(cn, cw, tt.newValue, utils.NewTemplateData(tt.newValue)); (err != nil) != tt.wantErr {
 				t.Errorf("YAMLPathVersionPatch.Apply() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
+
+func Test_setYAMLNode(t *testing.T) {
+	type patch struct {
+		path  string
+		value string
+	}
+	tests := []struct {
+		name    string
+		file    string
+		golden  string
+		patches []patch
+	}{
+		{
+			name:   "release vector",
+			file:   "test/yaml-node/release.yaml",
+			golden: "test/yaml-node/release.golden.yaml",
+			patches: []patch{
+				{path: "docker-images.metal-stack.control-plane.metal-api.tag", value: "v0.38.0"},
+				{path: "docker-images.metal-stack.control-plane.metal-console-internal.tag", value: "v0.8.0"},
+				{path: "binaries.metal-stack.metalctl.version", value: "v0.18.0"},
+				{path: "docker-images.metal-stack.partition.metal-core.tag", value: "v0.13.0"},
+				{path: "ansible-roles.metal-roles.version", value: "v0.15.0"},
+			},
+		},
+		{
+			name:   "helm values",
+			file:   "test/yaml-node/values.yaml",
+			golden: "test/yaml-node/values.golden.yaml",
+			patches: []patch{
+				{path: "image.tag", value: "v0.38.0"},
+				{path: "sidecars.1.image", value: "ghcr.io/metal-stack/masterdata-api:v0.12.0"},
+			},
+		},
+		{
+			name:   "github workflow",
+			file:   "test/yaml-node/workflow.yaml",
+			golden: "test/yaml-node/workflow.golden.yaml",
+			patches: []patch{
+				{path: "env.GO_VERSION", value: "1.23"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := os.ReadFile(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want, err := os.ReadFile(tt.golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, p := range tt.patches {
+				content, err = setYAMLNode(content, p.path, p.value)
+				if err != nil {
+					t.Fatalf("setYAMLNode() error = %v", err)
+				}
+			}
+
+			if diff := cmp.Diff(string(want), string(content)); diff != "" {
+				t.Errorf("setYAMLNode() diff: %v", diff)
+			}
+		})
+	}
+}
+
+func Test_setYAMLNode_values(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		path    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "plain value that would change its type is quoted",
+			data:  "a: v1 # comment\n",
+			path:  "a",
+			value: "1.0",
+			want:  "a: \"1.0\" # comment\n",
+		},
+		{
+			name:  "numbers stay plain",
+			data:  "replicas: 1\n",
+			path:  "replicas",
+			value: "2",
+			want:  "replicas: 2\n",
+		},
+		{
+			name:  "single quotes are escaped",
+			data:  "a: 'b'\n",
+			path:  "a",
+			value: "it's",
+			want:  "a: 'it''s'\n",
+		},
+		{
+			name:  "double quoted value with escapes",
+			data:  "a: \"b\\\"c\" # \"comment\"\n",
+			path:  "a",
+			value: "d",
+			want:  "a: \"d\" # \"comment\"\n",
+		},
+		{
+			name:  "escaped dots in path",
+			data:  "annotations:\n  metal-stack.io/version: v1\n",
+			path:  "annotations.metal-stack\\.io/version",
+			value: "v2",
+			want:  "annotations:\n  metal-stack.io/version: v2\n",
+		},
+		{
+			name:  "flow style",
+			data:  "a: {b: v1, c: [v1, v2]}\n",
+			path:  "a.c.1",
+			value: "v3",
+			want:  "a: {b: v1, c: [v1, v3]}\n",
+		},
+		{
+			name:    "path not found",
+			data:    "a: b\n",
+			path:    "c",
+			value:   "d",
+			wantErr: true,
+		},
+		{
+			name:    "path to a mapping",
+			data:    "a:\n  b: c\n",
+			path:    "a",
+			value:   "d",
+			wantErr: true,
+		},
+		{
+			name:    "block scalar",
+			data:    "a: |\n  b\n",
+			path:    "a",
+			value:   "d",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := setYAMLNode([]byte(tt.data), tt.path, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("setYAMLNode() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("setYAMLNode() diff: %v", diff)
+			}
+		})
+	}
+}
+
+func TestYAMLPathVersionPatches_ApplyMultiDocument(t *testing.T) {
+	tpl := "ghcr.io/metal-stack/metal-api:%s"
+	path := "spec.template.spec.containers.0.image"
+
+	input, err := os.ReadFile("test/yaml-node/manifests.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		deployment = strings.Replace(string(input), "metal-api:v0.37.2 # updated", "metal-api:v0.38.0 # updated", 1)
+		both       = strings.ReplaceAll(string(input), "metal-api:v0.37.2", "metal-api:v0.38.0")
+	)
+
+	tests := []struct {
+		name    string
+		p       YAMLPathPatch
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "select document by index",
+			p:    YAMLPathPatch{file: "manifests.yaml", yamlPath: path, template: &tpl, versionCompare: true, mode: YAMLPatchModeNode, document: new(1)},
+			want: deployment,
+		},
+		{
+			name: "select documents by matcher",
+			p:    YAMLPathPatch{file: "manifests.yaml", yamlPath: path, template: &tpl, versionCompare: true, mode: YAMLPatchModeNode, documentMatch: map[string]string{"kind": "Deployment", "metadata.name": "metal-api"}},
+			want: deployment,
+		},
+		{
+			name: "patch all matching documents",
+			p:    YAMLPathPatch{file: "manifests.yaml", yamlPath: path, template: &tpl, versionCompare: true, mode: YAMLPatchModeNode, documentMatch: map[string]string{"spec.template.spec.containers.0.name": "metal-api"}},
+			want: both,
+		},
+		{
+			name:    "no document matches",
+			p:       YAMLPathPatch{file: "manifests.yaml", yamlPath: path, template: &tpl, mode: YAMLPatchModeNode, documentMatch: map[string]string{"kind": "StatefulSet"}},
+			wantErr: true,
+		},
+		{
+			name:    "document index out of range",
+			p:       YAMLPathPatch{file: "manifests.yaml", yamlPath: path, template: &tpl, mode: YAMLPatchModeNode, document: new(4)},
+			wantErr: true,
+		},
+		{
+			name: "convert mode only reformats the selected document",
+			p:    YAMLPathPatch{file: "manifests.yaml", yamlPath: "metadata.name", mode: YAMLPatchModeConvert, document: new(0)},
+			want: strings.Replace(string(input), "kind: ServiceAccount\nmetadata:\n  name: metal-api\n", "kind: ServiceAccount\nmetadata:\n  name: metal-robot\n", 1),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			cn := func(file string) ([]byte, error) {
+				return input, nil
+			}
+			cw := func(file string, content []byte) error {
+				got = string(content)
+				return nil
+			}
+
+			newValue := "v0.38.0"
+			if tt.p.mode == YAMLPatchModeConvert {
+				newValue = "metal-robot"
+			}
+
+			err := tt.p.Apply(cn, cw, newValue, utils.NewTemplateData(newValue))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("YAMLPathPatch.Apply() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("YAMLPathPatch.Apply() diff: %v", diff)
+			}
+		})
+	}
+}